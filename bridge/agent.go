@@ -0,0 +1,150 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sammcj/gomcp/llm"
+	"github.com/sammcj/gomcp/types"
+)
+
+// Answer is the model's reply to a conversation along with the tool calls
+// made to reach it
+type Answer struct {
+	Content string `json:"content"`
+	Steps   []Step `json:"steps,omitempty"`
+
+	// Messages holds the assistant and tool messages added to the
+	// conversation, ending with the final answer
+	Messages []types.Message `json:"-"`
+}
+
+// Step is one tool call made while answering
+type Step struct {
+	Iteration int                    `json:"iteration"`
+	Tool      string                 `json:"tool"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Output    string                 `json:"output"`
+	IsError   bool                   `json:"is_error,omitempty"`
+}
+
+// ProcessMessages runs a conversation through the LLM, feeding the results of
+// any tool calls back to it until it answers. After the configured number of
+// rounds of tool calls the model is asked once more with no tools offered, so
+// it answers from what it has. Cancelling ctx abandons any tool calls still
+// in flight.
+func (b *Bridge) ProcessMessages(ctx context.Context, messages []types.Message) (*Answer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 300*time.Second)
+	defer cancel()
+	stop := context.AfterFunc(b.ctx, cancel)
+	defer stop()
+
+	conversation := append([]types.Message(nil), messages...)
+	answer := &Answer{}
+	maxIterations := b.config.MaxToolIterations()
+
+	for iteration := 1; ; iteration++ {
+		final := iteration > maxIterations
+		if final && b.debug {
+			b.logger.Printf("Reached %d rounds of tool calls, asking for an answer without tools", maxIterations)
+		}
+		response, err := b.generateWithRetry(ctx, conversation, !final)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(response.ToolCalls) == 0 || final {
+			// Clean up any unwanted tags in the response
+			content := strings.ReplaceAll(response.Content, "<|im_start|>", "")
+			content = strings.ReplaceAll(content, "<|im_end|>", "")
+			answer.Content = strings.TrimSpace(content)
+			if final && answer.Content == "" {
+				answer.Content = fmt.Sprintf("[No answer after %d rounds of tool calls]", maxIterations)
+			}
+			answer.Messages = append(answer.Messages, types.Message{Role: "assistant", Content: answer.Content})
+			return answer, nil
+		}
+
+		// Tool results are matched to their calls by ID, which not every model supplies
+		toolCalls := response.ToolCalls
+		for n := range toolCalls {
+			if toolCalls[n].ID == "" {
+				toolCalls[n].ID = fmt.Sprintf("call_%d_%d", iteration, n)
+			}
+		}
+		if b.debug {
+			b.logger.Printf("Iteration %d: model requested %d tool call(s)", iteration, len(toolCalls))
+		}
+
+		toolResults := b.handleToolCalls(ctx, toolCalls)
+
+		for n, result := range toolResults {
+			result["name"] = toolCalls[n].Function.Name
+			output, _ := result["output"].(string)
+			isError, _ := result["is_error"].(bool)
+			answer.Steps = append(answer.Steps, Step{
+				Iteration: iteration,
+				Tool:      toolCalls[n].Function.Name,
+				Arguments: toolCalls[n].Function.Arguments,
+				Output:    output,
+				IsError:   isError,
+			})
+		}
+
+		added := append([]types.Message{{
+			Role:      "assistant",
+			Content:   response.Content,
+			ToolCalls: toolCalls,
+		}}, llm.ToolResultMessages(toolResults)...)
+		conversation = append(conversation, added...)
+		answer.Messages = append(answer.Messages, added...)
+	}
+}
+
+// generateWithRetry asks the LLM for the next message of a conversation,
+// retrying with backoff on transient errors. Tools are offered when withTools
+// is set.
+func (b *Bridge) generateWithRetry(ctx context.Context, messages []types.Message, withTools bool) (*types.LLMResponse, error) {
+	var response *types.LLMResponse
+	var err error
+
+	backoff := time.Second
+	for attempts := 0; attempts < 3; attempts++ {
+		if b.debug {
+			b.logger.Printf("Generating LLM response (attempt %d/3)", attempts+1)
+		}
+		response, err = b.generateLLMResponse(ctx, messages, withTools)
+		if err == nil {
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			// The turn was cancelled or timed out, so retrying can't help
+			return nil, ctx.Err()
+		}
+
+		if !isRetryableError(err) {
+			if b.debug {
+				b.logger.Printf("Non-retryable error encountered: %v", err)
+			}
+			return nil, err
+		}
+
+		if b.debug {
+			b.logger.Printf("Retrying after error: %v (attempt %d/3)", err, attempts+1)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+
+	return nil, &types.BridgeError{
+		Operation: "process_message",
+		Message:   "failed after retry attempts",
+		Err:       err,
+	}
+}
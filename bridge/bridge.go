@@ -21,17 +21,34 @@ import (
 
 // Bridge manages communication between MCP and LLM
 type Bridge struct {
-	ctx       context.Context
-	cancel    context.CancelFunc
-	llmClient *llm.Client
-	tools     []mcp.Tool
-	toolMap   map[string]string     // Maps sanitized tool names to original names
-	serverMap map[string]*MCPClient // Maps server names to their clients
-	dbTool    *tools.DatabaseTool
-	logger    *log.Logger
-	config    *config.Config
-	debug     bool
-	mu        sync.RWMutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	llmClient   *llm.Client
+	tools       []mcp.Tool
+	toolMap     map[string]toolRef     // Maps the names the LLM calls tools by to the tools they stand for
+	builtins    []mcp.Tool             // Tools implemented by the bridge itself
+	serverTools map[string][]mcp.Tool  // Maps server names to the tools they offer
+	serverMap   map[string]*MCPClient  // Maps server names to their clients
+	supervisors map[string]*supervisor // Maps server names to the supervisors restarting them
+	dbTool      *tools.DatabaseTool
+	resources   map[string][]Resource // Maps server names to the resources they offer
+	prompts     map[string][]Prompt   // Maps server names to the prompts they offer
+	roots       []string              // URIs of roots shared with every server
+	toolCache   *toolCache            // Tools of lazy servers from their last run
+	sessions    *sessionStore         // Conversations kept between messages
+	logger      *log.Logger
+	config      *config.Config
+	debug       bool
+	mu          sync.RWMutex
+	rebuildMu   sync.Mutex // serialises tool registry rebuilds
+
+	resourceUpdated  func(server, uri string)
+	stateChanged     func(ServerStatus)
+	samplingApprover SamplingApprover
+	usage            map[string]*TokenUsage // Maps server names to the tokens their sampling requests used
+
+	sessionMu    sync.Mutex
+	sessionLocks map[string]*sessionLock // Maps session IDs to the locks serialising their turns
 }
 
 // New creates a new Bridge instance
@@ -87,17 +104,73 @@ func New(cfg *config.Config, logger *log.Logger) (*Bridge, error) {
 		},
 	}
 
+	// Resolve the roots shared with every server
+	var roots []string
+	for _, root := range cfg.Roots {
+		uri, err := rootURI(root)
+		if err != nil {
+			cancel()
+			dbTool.Close()
+			return nil, &types.BridgeError{
+				Operation: "load_roots",
+				Message:   "invalid root",
+				Err:       err,
+			}
+		}
+		roots = append(roots, uri)
+	}
+
+	// Lazy servers offer the tools they had last time until they are started
+	var cache *toolCache
+	for _, serverCfg := range cfg.MCPServers {
+		if !serverCfg.Lazy {
+			continue
+		}
+		path, err := cfg.ToolCachePath()
+		if err != nil {
+			logger.Printf("Lazy servers will be started up front: no tool cache: %v", err)
+		} else {
+			cache = newToolCache(path)
+		}
+		break
+	}
+
+	// Open the store conversations are kept in
+	if debug {
+		logger.Printf("Opening session database: %s", cfg.SessionsPath())
+	}
+	sessions, err := openSessionStore(cfg.SessionsPath())
+	if err != nil {
+		cancel()
+		dbTool.Close()
+		return nil, &types.BridgeError{
+			Operation: "open_sessions",
+			Message:   "failed to open session database",
+			Err:       err,
+		}
+	}
+
 	bridge := &Bridge{
-		ctx:       ctx,
-		cancel:    cancel,
-		llmClient: llmClient,
-		tools:     []mcp.Tool{queryTool},
-		toolMap:   make(map[string]string),
-		serverMap: make(map[string]*MCPClient),
-		dbTool:    dbTool,
-		logger:    logger,
-		config:    cfg,
-		debug:     debug,
+		ctx:         ctx,
+		cancel:      cancel,
+		llmClient:   llmClient,
+		toolMap:     make(map[string]toolRef),
+		builtins:    []mcp.Tool{queryTool},
+		serverTools: make(map[string][]mcp.Tool),
+		serverMap:   make(map[string]*MCPClient),
+		supervisors: make(map[string]*supervisor),
+		dbTool:      dbTool,
+		resources:   make(map[string][]Resource),
+		prompts:     make(map[string][]Prompt),
+		usage:       make(map[string]*TokenUsage),
+		roots:       roots,
+		toolCache:   cache,
+		sessions:    sessions,
+		logger:      logger,
+		config:      cfg,
+		debug:       debug,
+
+		sessionLocks: make(map[string]*sessionLock),
 	}
 
 	if debug {
@@ -112,74 +185,62 @@ func (b *Bridge) Initialize() error {
 		b.logger.Println("Starting bridge initialization...")
 	}
 
-	// Register built-in tools
-	if b.debug {
-		b.logger.Println("Registering built-in tools...")
-	}
-	b.toolMap["query_database"] = "query_database"
-
 	// Initialize MCP servers
 	if b.debug {
 		b.logger.Printf("Initializing %d MCP servers...", len(b.config.MCPServers))
 	}
 
-	for _, serverCfg := range b.config.MCPServers {
-		if b.debug {
-			b.logger.Printf("Initializing MCP server: %s", serverCfg.Name)
-		}
+	// Start every server at once; each is ready when its initialize
+	// handshake completes. Lazy servers with cached tools wait for their
+	// first tool call instead.
+	results := make([]startupResult, len(b.config.MCPServers))
+	var wg sync.WaitGroup
+	for i, serverCfg := range b.config.MCPServers {
+		s := newSupervisor(b, serverCfg)
+		b.mu.Lock()
+		b.supervisors[serverCfg.Name] = s
+		b.mu.Unlock()
 
-		// Create client with environment variables
-		client, err := NewMCPClient(serverCfg.Command, serverCfg.Arguments, b.logger)
-		if err != nil {
-			return fmt.Errorf("failed to create MCP client for %s: %w", serverCfg.Name, err)
+		if serverCfg.Lazy && b.toolCache != nil {
+			if cached, ok := b.toolCache.Load(serverCfg.Name); ok {
+				s.startIdle(cached)
+				results[i] = startupResult{name: serverCfg.Name, idle: true}
+				continue
+			}
+			b.logger.Printf("No cached tools for lazy MCP server %s; starting it to list them", serverCfg.Name)
 		}
 
-		// Initialize the client
-		if b.debug {
-			b.logger.Printf("Initializing MCP client for %s...", serverCfg.Name)
-		}
-		_, err = client.Initialize(b.ctx, mcp.InitializeRequest{})
-		if err != nil {
-			client.Close()
-			return fmt.Errorf("failed to initialize MCP client for %s: %w", serverCfg.Name, err)
-		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started := time.Now()
+			err := s.start()
+			results[i] = startupResult{name: serverCfg.Name, duration: time.Since(started), err: err}
+		}()
+	}
+	wg.Wait()
 
-		// List tools from the server
-		if b.debug {
-			b.logger.Printf("Listing tools for %s...", serverCfg.Name)
-		}
-		toolsResult, err := client.ListTools(b.ctx, mcp.ListToolsRequest{})
-		if err != nil {
-			client.Close()
-			return fmt.Errorf("failed to list tools for %s: %w", serverCfg.Name, err)
-		}
+	b.logger.Print(formatStartupSummary(results))
 
-		// Register server's tools
-		for _, tool := range toolsResult.Tools {
-			sanitizedName := sanitizeToolName(tool.Name)
-			b.toolMap[sanitizedName] = fmt.Sprintf("%s/%s", serverCfg.Name, tool.Name)
-			b.tools = append(b.tools, tool)
-			if b.debug {
-				b.logger.Printf("Registered tool %s from server %s", tool.Name, serverCfg.Name)
-			}
+	// Only required servers stop the bridge from starting
+	var errs []error
+	for i, result := range results {
+		if result.err == nil {
+			continue
 		}
-
-		// Store the client
-		b.mu.Lock()
-		b.serverMap[serverCfg.Name] = client
-		b.mu.Unlock()
-
-		if b.debug {
-			b.logger.Printf("MCP server %s initialized with %d tools", serverCfg.Name, len(toolsResult.Tools))
+		if !b.config.MCPServers[i].IsRequired() {
+			b.logger.Printf("Continuing without optional MCP server %s: %v", result.name, result.err)
+			continue
 		}
+		errs = append(errs, result.err)
 	}
-
-	// Set tools in LLM client
-	if b.debug {
-		b.logger.Printf("Setting %d tools in LLM client...", len(b.tools))
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
-	if err := b.llmClient.SetTools(b.tools); err != nil {
-		return fmt.Errorf("failed to set tools in LLM client: %w", err)
+
+	// Register every server's tools with the LLM
+	if err := b.rebuildTools(); err != nil {
+		return err
 	}
 
 	if b.debug {
@@ -188,161 +249,246 @@ func (b *Bridge) Initialize() error {
 	return nil
 }
 
-// ProcessMessage handles a message from the user through the LLM and tools
-func (b *Bridge) ProcessMessage(msg string) (string, error) {
+// connectServer launches or connects to an MCP server, performs the
+// initialize handshake and registers the server's tools, resources and
+// prompts. The caller rebuilds the tool registry afterwards.
+func (b *Bridge) connectServer(serverCfg config.MCPServerConfig) (*MCPClient, error) {
 	if b.debug {
-		b.logger.Printf("Processing message: %s", msg)
+		b.logger.Printf("Initializing MCP server: %s", serverCfg.Name)
 	}
-	_, cancel := context.WithTimeout(b.ctx, 300*time.Second)
-	defer cancel()
 
-	var response *types.LLMResponse
-	var err error
+	// The server has its startup timeout to launch and finish the handshake
+	startCtx, cancel := context.WithTimeout(b.ctx, serverCfg.StartTimeout())
+	defer cancel()
 
-	// Implement retry with backoff
-	backoff := time.Second
-	for attempts := 0; attempts < 3; attempts++ {
-		if b.debug {
-			b.logger.Printf("Generating LLM response (attempt %d/3)", attempts+1)
-		}
-		response, err = b.generateLLMResponse(msg)
-		if err == nil {
-			break
-		}
+	// Create client with environment variables
+	client, err := NewMCPClient(startCtx, serverCfg, b.logger, b.config.Logging.Level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MCP client for %s: %w", serverCfg.Name, startupError(startCtx, serverCfg, err))
+	}
 
-		if !isRetryableError(err) {
-			if b.debug {
-				b.logger.Printf("Non-retryable error encountered: %v", err)
-			}
-			return "", err
-		}
+	// Let the server request completions, as its sampling policy allows
+	b.enableSampling(serverCfg, client)
+	b.enableRoots(serverCfg, client)
+	b.watchLogs(serverCfg, client)
 
-		if b.debug {
-			b.logger.Printf("Retrying after error: %v (attempt %d/3)", err, attempts+1)
-		}
-		time.Sleep(backoff)
-		backoff *= 2
+	// Initialize the client
+	if b.debug {
+		b.logger.Printf("Initializing MCP client for %s...", serverCfg.Name)
+	}
+	initResult, err := client.Initialize(startCtx, mcp.InitializeRequest{})
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to initialize MCP client for %s: %w", serverCfg.Name, startupError(startCtx, serverCfg, err))
+	}
+	if b.debug {
+		b.logger.Printf("MCP server %s is %s %s (protocol %s)", serverCfg.Name,
+			initResult.ServerInfo.Name, initResult.ServerInfo.Version, initResult.ProtocolVersion)
 	}
 
+	b.applyLogLevel(serverCfg, client)
+
+	// List tools from the server
+	if b.debug {
+		b.logger.Printf("Listing tools for %s...", serverCfg.Name)
+	}
+	listCtx, cancel := context.WithTimeout(b.ctx, serverCfg.RequestTimeout())
+	toolsResult, err := client.ListTools(listCtx, mcp.ListToolsRequest{})
+	cancel()
 	if err != nil {
-		return "", &types.BridgeError{
-			Operation: "process_message",
-			Message:   "failed after retry attempts",
-			Err:       err,
-		}
+		client.Close()
+		return nil, fmt.Errorf("failed to list tools for %s: %w", serverCfg.Name, err)
 	}
 
-	// Process tool calls if present
-	if len(response.ToolCalls) > 0 {
-		toolResults, err := b.handleToolCalls(response.ToolCalls)
-		if err != nil {
-			if b.debug {
-				b.logger.Printf("Tool execution failed: %v", err)
-			}
-			return "", &types.BridgeError{
-				Operation: "handle_tools",
-				Message:   "tool execution failed",
-				Err:       err,
-			}
-		}
+	// Store the client and its tools, and follow changes to them
+	b.mu.Lock()
+	if b.serverMap == nil {
+		b.mu.Unlock()
+		client.Close()
+		return nil, fmt.Errorf("bridge closed while connecting to %s", serverCfg.Name)
+	}
+	b.serverMap[serverCfg.Name] = client
+	b.serverTools[serverCfg.Name] = toolsResult.Tools
+	b.mu.Unlock()
+	b.cacheTools(serverCfg.Name, toolsResult.Tools)
+	b.watchTools(serverCfg.Name, client)
 
-		if len(toolResults) > 0 {
-			return toolResults[0]["output"].(string), nil
-		}
+	// Load the server's resources and prompts, if it has any
+	b.watchResources(serverCfg.Name, client)
+	b.watchPrompts(serverCfg.Name, client)
+
+	if b.debug {
+		b.logger.Printf("MCP server %s initialized with %d tools", serverCfg.Name, len(toolsResult.Tools))
 	}
+	return client, nil
+}
 
-	// Clean up any unwanted tags in the response
-	content := strings.ReplaceAll(response.Content, "<|im_start|>", "")
-	content = strings.ReplaceAll(content, "<|im_end|>", "")
-	content = strings.TrimSpace(content)
+// disconnectServer forgets a server that has stopped, along with everything
+// it offered. A lazy server keeps its tools, as calling one starts it again.
+func (b *Bridge) disconnectServer(name string) {
+	lazy := b.serverConfig(name).Lazy
 
-	return content, nil
+	b.mu.Lock()
+	delete(b.serverMap, name)
+	if !lazy {
+		delete(b.serverTools, name)
+	}
+	delete(b.resources, name)
+	delete(b.prompts, name)
+	b.mu.Unlock()
 }
 
-// handleToolCalls processes tool invocations from the LLM
-func (b *Bridge) handleToolCalls(toolCalls []types.ToolCall) ([]map[string]interface{}, error) {
-	var results []map[string]interface{}
+// ProcessMessage handles a message from the user through the LLM and tools.
+// Cancelling ctx abandons any tool calls still in flight.
+func (b *Bridge) ProcessMessage(ctx context.Context, msg string) (string, error) {
+	if b.debug {
+		b.logger.Printf("Processing message: %s", msg)
+	}
+	answer, err := b.ProcessMessages(ctx, []types.Message{{Role: "user", Content: msg}})
+	if err != nil {
+		return "", err
+	}
+	return answer.Content, nil
+}
 
-	for _, call := range toolCalls {
-		// Get original tool name and server
-		mcpName, ok := b.toolMap[call.Function.Name]
-		if !ok {
-			if b.debug {
-				b.logger.Printf("Unknown tool requested: %s", call.Function.Name)
-			}
-			return nil, fmt.Errorf("unknown tool: %s", call.Function.Name)
-		}
+// handleToolCalls runs the tool calls the LLM asked for in one turn, up to
+// the configured number at once. Results keep the order of the calls, and a
+// call that fails gets an error result rather than failing the others.
+func (b *Bridge) handleToolCalls(ctx context.Context, toolCalls []types.ToolCall) []map[string]interface{} {
+	results := make([]map[string]interface{}, len(toolCalls))
+
+	limit := make(chan struct{}, b.config.ParallelToolLimit())
+	var wg sync.WaitGroup
+	for n, call := range toolCalls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			results[n] = b.handleToolCall(ctx, call)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
 
-		// Handle built-in database tool
-		if mcpName == "query_database" {
-			result, err := b.handleDatabaseTool(call)
-			if err != nil {
-				return nil, err
-			}
-			results = append(results, result)
-			continue
+// handleToolCall runs one tool call, reporting any failure as its result
+func (b *Bridge) handleToolCall(ctx context.Context, call types.ToolCall) map[string]interface{} {
+	// Get original tool name and server
+	ref, ok := b.lookupTool(call.Function.Name)
+	if !ok {
+		if b.debug {
+			b.logger.Printf("Unknown tool requested: %s", call.Function.Name)
 		}
 
-		// Parse server and tool name
-		parts := strings.SplitN(mcpName, "/", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid tool mapping: %s", mcpName)
+		// The tool may have been removed by its server since the LLM last saw the list
+		return toolErrorResult(call, fmt.Sprintf("Tool %s is not available. It may have been removed by its server.", call.Function.Name))
+	}
+
+	// Models often get argument types wrong, so fit them to the tool's schema
+	if schema, ok := b.toolSchema(call.Function.Name); ok {
+		var changes []string
+		call.Function.Arguments, changes = coerceArguments(schema, call.Function.Arguments)
+		for _, change := range changes {
+			b.logger.Printf("Coerced argument of tool %s: %s", call.Function.Name, change)
 		}
-		serverName, toolName := parts[0], parts[1]
-
-		// Get the MCP client
-		b.mu.RLock()
-		client, ok := b.serverMap[serverName]
-		b.mu.RUnlock()
-		if !ok {
-			return nil, fmt.Errorf("unknown MCP server: %s", serverName)
+	}
+
+	// Handle built-in database tool
+	if ref.Server == "" && ref.Tool == "query_database" {
+		result, err := b.handleDatabaseTool(call)
+		if err != nil {
+			return toolErrorResult(call, fmt.Sprintf("Tool %s failed: %v", call.Function.Name, err))
 		}
+		return result
+	}
 
-		// Convert numeric arguments to strings for enum fields
-		convertedArgs := make(map[string]interface{})
-		for k, v := range call.Function.Arguments {
-			switch val := v.(type) {
-			case float64:
-				// Convert numeric values to strings for known numeric enum fields
-				if k == "limit" || k == "interval" {
-					convertedArgs[k] = fmt.Sprintf("%v", val)
-				} else {
-					convertedArgs[k] = val
-				}
-			default:
-				convertedArgs[k] = val
-			}
+	// Handle built-in resource tools
+	if ref.Server == "" && (ref.Tool == listResourcesTool || ref.Tool == readResourceTool) {
+		result, err := b.handleResourceTool(ctx, call)
+		if err != nil {
+			return toolErrorResult(call, fmt.Sprintf("Tool %s failed: %v", call.Function.Name, err))
 		}
+		return result
+	}
+
+	serverName, toolName := ref.Server, ref.Tool
 
-		// Execute the tool with converted arguments
+	// Get the MCP client, starting a lazy server if need be
+	b.mu.RLock()
+	supervisor, ok := b.supervisors[serverName]
+	b.mu.RUnlock()
+	if !ok {
+		return toolErrorResult(call, fmt.Sprintf("Tool %s belongs to unknown MCP server %s", call.Function.Name, serverName))
+	}
+	client, err := supervisor.acquire()
+	if err != nil {
 		if b.debug {
-			b.logger.Printf("Executing tool %s on server %s with arguments: %v", toolName, serverName, convertedArgs)
+			b.logger.Printf("MCP server %s unavailable for %s: %v", serverName, toolName, err)
 		}
-		result, err := client.CallTool(b.ctx, mcp.CallToolRequest{
-			Params: struct {
-				Name      string                 `json:"name"`
-				Arguments map[string]interface{} `json:"arguments,omitempty"`
-			}{
-				Name:      toolName,
-				Arguments: convertedArgs,
-			},
+		return toolErrorResult(call, fmt.Sprintf("Tool %s is unavailable: %v", call.Function.Name, err))
+	}
+	defer supervisor.release()
+
+	// Execute the tool
+	if b.debug {
+		b.logger.Printf("Executing tool %s on server %s with arguments: %v", toolName, serverName, call.Function.Arguments)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, b.serverConfig(serverName).ToolTimeout(toolName))
+	defer cancel()
+	if report := progressHandler(ctx); report != nil {
+		callCtx = WithProgress(callCtx, func(p Progress) {
+			p.Server, p.Tool = serverName, toolName
+			report(p)
 		})
-		if err != nil {
-			if b.debug {
-				b.logger.Printf("Tool execution failed: %v", err)
-			}
-			return nil, fmt.Errorf("tool execution failed: %w", err)
+	}
+	result, err := client.CallTool(callCtx, mcp.CallToolRequest{
+		Params: struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments,omitempty"`
+		}{
+			Name:      toolName,
+			Arguments: call.Function.Arguments,
+		},
+	})
+	if err != nil {
+		if b.debug {
+			b.logger.Printf("Tool execution failed: %v", err)
 		}
 
-		// Format the result
-		formattedResult := b.formatToolResult(result)
-		results = append(results, map[string]interface{}{
-			"tool_call_id": call.ID,
-			"output":       formattedResult,
-		})
+		// A rejection is the server's answer; anything else means the call didn't complete
+		var rpcErr *types.RPCError
+		if errors.As(err, &rpcErr) {
+			return toolErrorResult(call, fmt.Sprintf("Tool %s rejected the call: %s", toolName, rpcErr.Message))
+		}
+		return toolErrorResult(call, fmt.Sprintf("Tool %s failed: %v", toolName, err))
+	}
+
+	// Format the result, passing on images for models that accept them
+	formattedResult := b.formatToolResult(result)
+	toolResult := map[string]interface{}{
+		"tool_call_id": call.ID,
+		"output":       formattedResult,
 	}
+	if result.IsError {
+		// The tool ran but failed; the model should see that rather than a success
+		toolResult["output"] = fmt.Sprintf("Tool %s returned an error: %s", toolName, formattedResult)
+		toolResult["is_error"] = true
+	}
+	if images := resultImages(result); len(images) > 0 {
+		toolResult["images"] = images
+	}
+	return toolResult
+}
 
-	return results, nil
+// toolErrorResult reports a tool call that failed, so the model sees what went wrong
+func toolErrorResult(call types.ToolCall, output string) map[string]interface{} {
+	return map[string]interface{}{
+		"tool_call_id": call.ID,
+		"output":       output,
+		"is_error":     true,
+	}
 }
 
 // handleDatabaseTool processes database tool calls
@@ -378,21 +524,8 @@ func (b *Bridge) formatToolResult(result *mcp.CallToolResult) string {
 
 	var output strings.Builder
 	for _, content := range result.Content {
-		switch v := content.(type) {
-		case map[string]interface{}:
-			if text, ok := v["text"].(string); ok {
-				output.WriteString(text)
-				output.WriteString("\n")
-			} else if data, err := json.MarshalIndent(v, "", "  "); err == nil {
-				output.Write(data)
-				output.WriteString("\n")
-			}
-		default:
-			if data, err := json.MarshalIndent(v, "", "  "); err == nil {
-				output.Write(data)
-				output.WriteString("\n")
-			}
-		}
+		output.WriteString(formatContent(content))
+		output.WriteString("\n")
 	}
 	return strings.TrimSpace(output.String())
 }
@@ -486,20 +619,37 @@ func (b *Bridge) Close() error {
 	if err := b.dbTool.Close(); err != nil {
 		errs = append(errs, fmt.Errorf("database tool: %w", err))
 	}
+	if err := b.sessions.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("session database: %w", err))
+	}
 
-	// Close all MCP clients
+	// Stop all MCP servers at once, since each may take a while to exit
 	b.mu.Lock()
-	for name, client := range b.serverMap {
-		if err := client.Close(); err != nil {
-			errs = append(errs, fmt.Errorf("MCP server %s: %w", name, err))
-		}
-	}
+	clients := b.serverMap
 	b.serverMap = nil
 	b.mu.Unlock()
 
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	for name, client := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Close(); err != nil {
+				errMu.Lock()
+				errs = append(errs, fmt.Errorf("MCP server %s: %w", name, err))
+				errMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
 	// Clear other resources
+	b.mu.Lock()
 	b.toolMap = nil
 	b.tools = nil
+	b.serverTools = nil
+	b.mu.Unlock()
 
 	if len(errs) > 0 {
 		return fmt.Errorf("multiple close errors: %v", errs)
@@ -510,23 +660,23 @@ func (b *Bridge) Close() error {
 
 // Helper functions
 
-// sanitizeToolName converts a tool name to a format compatible with LLMs
-func sanitizeToolName(name string) string {
-	// Replace characters that might cause issues
-	sanitized := ""
-	for _, r := range name {
-		if r == '-' || r == ' ' {
-			sanitized += "_"
-		} else {
-			sanitized += string(r)
+// serverConfig returns the configuration for the named MCP server
+func (b *Bridge) serverConfig(name string) config.MCPServerConfig {
+	for _, serverCfg := range b.config.MCPServers {
+		if serverCfg.Name == name {
+			return serverCfg
 		}
 	}
-	return sanitized
+	return config.MCPServerConfig{Name: name}
 }
 
-// generateLLMResponse sends a message to the LLM and gets a response
-func (b *Bridge) generateLLMResponse(msg string) (*types.LLMResponse, error) {
-	resp, err := b.llmClient.GenerateResponse(msg)
+// generateLLMResponse sends a conversation to the LLM and gets a response
+func (b *Bridge) generateLLMResponse(ctx context.Context, messages []types.Message, withTools bool) (*types.LLMResponse, error) {
+	generate := b.llmClient.GenerateChatWithoutTools
+	if withTools {
+		generate = b.llmClient.GenerateChat
+	}
+	resp, err := generate(ctx, messages)
 	if err != nil {
 		if b.debug {
 			b.logger.Printf("LLM response generation failed: %v", err)
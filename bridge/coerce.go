@@ -0,0 +1,234 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// coerceArguments adjusts the arguments of a tool call to the types its
+// input schema declares, the way small models often get them wrong: numbers
+// sent as strings and the reverse, objects sent as JSON strings, fractions
+// where integers are expected, and missing arguments that have defaults. It
+// returns the adjusted arguments and a description of each change made.
+func coerceArguments(schema mcp.ToolInputSchema, args map[string]interface{}) (map[string]interface{}, []string) {
+	var changes []string
+	coerced := coerceObject("", schema.Properties, args, &changes)
+	return coerced, changes
+}
+
+// coerceObject coerces the members of an object against the schemas of its
+// properties, filling in declared defaults for missing ones
+func coerceObject(path string, properties map[string]interface{}, args map[string]interface{}, changes *[]string) map[string]interface{} {
+	coerced := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		coerced[k] = v
+	}
+
+	for name, raw := range properties {
+		prop, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		argPath := name
+		if path != "" {
+			argPath = path + "." + name
+		}
+
+		value, present := coerced[name]
+		if !present {
+			if def, ok := prop["default"]; ok {
+				coerced[name] = def
+				*changes = append(*changes, fmt.Sprintf("%s: filled in default %s", argPath, describeValue(def)))
+			}
+			continue
+		}
+		coerced[name] = coerceValue(argPath, prop, value, changes)
+	}
+	return coerced
+}
+
+// coerceValue converts a value to the type its schema declares if it isn't
+// already, leaving it alone when no conversion makes sense
+func coerceValue(path string, prop map[string]interface{}, value interface{}, changes *[]string) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	types := schemaTypes(prop)
+	if len(types) == 0 {
+		return value
+	}
+
+	// A value that already fits only needs its members checked
+	for _, t := range types {
+		if !matchesType(t, value) {
+			continue
+		}
+		if t == "integer" {
+			// JSON numbers decode as floats, so whole numbers already fit
+			return value
+		}
+		return coerceMembers(path, prop, value, changes)
+	}
+
+	for _, t := range types {
+		converted, ok := convertValue(t, value)
+		if !ok {
+			continue
+		}
+		*changes = append(*changes, fmt.Sprintf("%s: converted %s to %s", path, describeValue(value), describeValue(converted)))
+		return coerceMembers(path, prop, converted, changes)
+	}
+	return value
+}
+
+// coerceMembers coerces the properties of an object or the items of an array
+func coerceMembers(path string, prop map[string]interface{}, value interface{}, changes *[]string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if properties, ok := prop["properties"].(map[string]interface{}); ok {
+			return coerceObject(path, properties, v, changes)
+		}
+	case []interface{}:
+		if items, ok := prop["items"].(map[string]interface{}); ok {
+			coerced := make([]interface{}, len(v))
+			for n, item := range v {
+				coerced[n] = coerceValue(fmt.Sprintf("%s[%d]", path, n), items, item, changes)
+			}
+			return coerced
+		}
+	}
+	return value
+}
+
+// schemaTypes returns the types a schema allows, which JSON Schema gives as
+// either a single name or a list
+func schemaTypes(prop map[string]interface{}) []string {
+	switch t := prop["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, name := range t {
+			if s, ok := name.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesType reports whether a decoded JSON value is of a JSON Schema type
+func matchesType(t string, value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return t == "string"
+	case bool:
+		return t == "boolean"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case int, int64:
+		return t == "number" || t == "integer"
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	}
+	return false
+}
+
+// convertValue converts a value to a JSON Schema type, reporting whether it could
+func convertValue(t string, value interface{}) (interface{}, bool) {
+	switch t {
+	case "string":
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+
+	case "number":
+		if s, ok := value.(string); ok {
+			return parseNumber(s)
+		}
+
+	case "integer":
+		switch v := value.(type) {
+		case float64:
+			return int64(math.Round(v)), true
+		case string:
+			if f, ok := parseNumber(v); ok {
+				return int64(math.Round(f)), true
+			}
+		}
+
+	case "boolean":
+		if s, ok := value.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, true
+			}
+		}
+
+	case "object":
+		if s, ok := value.(string); ok {
+			var obj map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &obj); err == nil && obj != nil {
+				return obj, true
+			}
+		}
+
+	case "array":
+		if s, ok := value.(string); ok {
+			var arr []interface{}
+			if err := json.Unmarshal([]byte(s), &arr); err == nil && arr != nil {
+				return arr, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// parseNumber parses a number sent as a string, refusing NaN and infinities
+// since JSON can't carry them
+func parseNumber(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// describeValue renders a value with its JSON type for coercion logs
+func describeValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", value))
+	}
+	text := string(data)
+	if len(text) > 60 {
+		text = text[:57] + "..."
+	}
+
+	switch value.(type) {
+	case string:
+		return "string " + text
+	case float64:
+		return "number " + text
+	case int, int64:
+		return "integer " + text
+	case bool:
+		return "boolean " + text
+	case map[string]interface{}:
+		return "object " + text
+	case []interface{}:
+		return "array " + text
+	}
+	return text
+}
@@ -0,0 +1,176 @@
+package bridge
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCoerceArguments(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties string // JSON Schema properties of the tool's input
+		args       string
+		want       string
+		changes    []string
+	}{
+		{
+			name:       "number to string",
+			properties: `{"limit": {"type": "string"}}`,
+			args:       `{"limit": 5}`,
+			want:       `{"limit": "5"}`,
+			changes:    []string{`limit: converted number 5 to string "5"`},
+		},
+		{
+			name:       "string to number",
+			properties: `{"ratio": {"type": "number"}}`,
+			args:       `{"ratio": " 0.5 "}`,
+			want:       `{"ratio": 0.5}`,
+			changes:    []string{`ratio: converted string " 0.5 " to number 0.5`},
+		},
+		{
+			name:       "string that isn't a number",
+			properties: `{"ratio": {"type": "number"}}`,
+			args:       `{"ratio": "NaN"}`,
+			want:       `{"ratio": "NaN"}`,
+		},
+		{
+			name:       "fraction rounded to integer",
+			properties: `{"count": {"type": "integer"}}`,
+			args:       `{"count": 2.6}`,
+			want:       `{"count": 3}`,
+			changes:    []string{`count: converted number 2.6 to integer 3`},
+		},
+		{
+			name:       "string rounded to integer",
+			properties: `{"count": {"type": "integer"}}`,
+			args:       `{"count": "7.2"}`,
+			want:       `{"count": 7}`,
+			changes:    []string{`count: converted string "7.2" to integer 7`},
+		},
+		{
+			name:       "whole number is already an integer",
+			properties: `{"count": {"type": "integer"}}`,
+			args:       `{"count": 3}`,
+			want:       `{"count": 3}`,
+		},
+		{
+			name:       "string to boolean",
+			properties: `{"flag": {"type": "boolean"}}`,
+			args:       `{"flag": "true"}`,
+			want:       `{"flag": true}`,
+			changes:    []string{`flag: converted string "true" to boolean true`},
+		},
+		{
+			name:       "object sent as a JSON string",
+			properties: `{"filter": {"type": "object", "properties": {"n": {"type": "integer"}}}}`,
+			args:       `{"filter": "{\"n\": \"4\"}"}`,
+			want:       `{"filter": {"n": 4}}`,
+			changes: []string{
+				`filter: converted string "{\"n\": \"4\"}" to object {"n":"4"}`,
+				`filter.n: converted string "4" to integer 4`,
+			},
+		},
+		{
+			name:       "array sent as a JSON string",
+			properties: `{"ids": {"type": "array", "items": {"type": "integer"}}}`,
+			args:       `{"ids": "[\"1\", 2]"}`,
+			want:       `{"ids": [1, 2]}`,
+			changes: []string{
+				`ids: converted string "[\"1\", 2]" to array ["1",2]`,
+				`ids[0]: converted string "1" to integer 1`,
+			},
+		},
+		{
+			name:       "nested items",
+			properties: `{"rows": {"type": "array", "items": {"type": "object", "properties": {"id": {"type": "string"}}}}}`,
+			args:       `{"rows": [{"id": 1}, {"id": "2"}]}`,
+			want:       `{"rows": [{"id": "1"}, {"id": "2"}]}`,
+			changes:    []string{`rows[0].id: converted number 1 to string "1"`},
+		},
+		{
+			name:       "defaults fill in missing arguments",
+			properties: `{"mode": {"type": "string", "default": "fast"}, "filter": {"type": "object", "properties": {"d": {"type": "string", "default": "x"}}}}`,
+			args:       `{"filter": {}}`,
+			want:       `{"mode": "fast", "filter": {"d": "x"}}`,
+			changes: []string{
+				`filter.d: filled in default string "x"`,
+				`mode: filled in default string "fast"`,
+			},
+		},
+		{
+			name:       "present arguments keep their value over the default",
+			properties: `{"mode": {"type": "string", "default": "fast"}}`,
+			args:       `{"mode": "slow"}`,
+			want:       `{"mode": "slow"}`,
+		},
+		{
+			name:       "union type accepts null",
+			properties: `{"opt": {"type": ["string", "null"]}}`,
+			args:       `{"opt": null}`,
+			want:       `{"opt": null}`,
+		},
+		{
+			name:       "untyped and unknown arguments are left alone",
+			properties: `{"free": {}}`,
+			args:       `{"free": 1, "extra": "kept"}`,
+			want:       `{"free": 1, "extra": "kept"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema mcp.ToolInputSchema
+			schema.Type = "object"
+			if err := json.Unmarshal([]byte(tt.properties), &schema.Properties); err != nil {
+				t.Fatalf("invalid properties: %v", err)
+			}
+			var args map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.args), &args); err != nil {
+				t.Fatalf("invalid args: %v", err)
+			}
+			original, _ := json.Marshal(args)
+
+			got, changes := coerceArguments(schema, args)
+
+			if !reflect.DeepEqual(decodeJSON(t, got), decodeJSON(t, tt.want)) {
+				data, _ := json.Marshal(got)
+				t.Errorf("arguments = %s, want %s", data, tt.want)
+			}
+			// Properties are visited in map order, so compare the changes sorted
+			wantChanges := append([]string(nil), tt.changes...)
+			sort.Strings(changes)
+			sort.Strings(wantChanges)
+			if len(changes) != 0 || len(wantChanges) != 0 {
+				if !reflect.DeepEqual(changes, wantChanges) {
+					t.Errorf("changes = %q, want %q", changes, wantChanges)
+				}
+			}
+			if after, _ := json.Marshal(args); string(after) != string(original) {
+				t.Errorf("arguments passed in were modified: %s", after)
+			}
+		})
+	}
+}
+
+// decodeJSON round-trips a value, or decodes a JSON string, so values can be
+// compared without regard to Go's numeric types
+func decodeJSON(t *testing.T, value interface{}) interface{} {
+	t.Helper()
+	data, ok := value.(string)
+	if !ok {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("failed to encode %v: %v", value, err)
+		}
+		data = string(encoded)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("failed to decode %s: %v", data, err)
+	}
+	return decoded
+}
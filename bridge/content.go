@@ -0,0 +1,103 @@
+package bridge
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// AudioContent is audio returned by a tool. mcp-go predates audio content,
+// so the bridge defines it itself.
+type AudioContent struct {
+	mcp.Annotated
+	Type     string `json:"type"`
+	Data     string `json:"data"`
+	MIMEType string `json:"mimeType"`
+}
+
+// EmbeddedResource is a resource embedded in a tool result. mcp-go's
+// EmbeddedResource keeps only the URI and MIME type of the resource, so the
+// bridge defines its own that carries the text or blob as well.
+type EmbeddedResource struct {
+	mcp.Annotated
+	Type     string           `json:"type"`
+	Resource ResourceContents `json:"resource"`
+}
+
+// decodeContent converts one item of a tool result's content to the matching
+// mcp-go or bridge type. Items of unknown types are kept as generic maps.
+func decodeContent(raw json.RawMessage) (interface{}, error) {
+	var item struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return nil, fmt.Errorf("invalid content item: %w", err)
+	}
+
+	switch item.Type {
+	case "text":
+		var content mcp.TextContent
+		err := json.Unmarshal(raw, &content)
+		return content, err
+	case "image":
+		var content mcp.ImageContent
+		err := json.Unmarshal(raw, &content)
+		return content, err
+	case "audio":
+		var content AudioContent
+		err := json.Unmarshal(raw, &content)
+		return content, err
+	case "resource":
+		var content EmbeddedResource
+		if err := json.Unmarshal(raw, &content); err != nil {
+			return nil, fmt.Errorf("invalid embedded resource: %w", err)
+		}
+		return content, nil
+	default:
+		var content map[string]interface{}
+		err := json.Unmarshal(raw, &content)
+		return content, err
+	}
+}
+
+// formatContent renders one content item as text for the LLM and the user
+func formatContent(content interface{}) string {
+	switch v := content.(type) {
+	case mcp.TextContent:
+		return v.Text
+	case mcp.ImageContent:
+		return fmt.Sprintf("[image: %s, about %d bytes]", v.MIMEType, base64.StdEncoding.DecodedLen(len(v.Data)))
+	case AudioContent:
+		return fmt.Sprintf("[audio: %s, about %d bytes]", v.MIMEType, base64.StdEncoding.DecodedLen(len(v.Data)))
+	case EmbeddedResource:
+		return FormatResourceContents([]ResourceContents{v.Resource})
+	case map[string]interface{}:
+		if text, ok := v["text"].(string); ok {
+			return text
+		}
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", content)
+	}
+	return string(data)
+}
+
+// resultImages returns the base64-encoded images in a tool result, for
+// models that accept images
+func resultImages(result *mcp.CallToolResult) []string {
+	if result == nil {
+		return nil
+	}
+
+	var images []string
+	for _, content := range result.Content {
+		if image, ok := content.(mcp.ImageContent); ok && image.Data != "" {
+			images = append(images, image.Data)
+		}
+	}
+	return images
+}
@@ -0,0 +1,166 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sammcj/gomcp/config"
+)
+
+// logLevels orders the logging levels from most to least verbose
+var logLevels = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+}
+
+// levelEnabled reports whether a message at level is logged when the
+// configured logging level is threshold. Unknown thresholds count as info,
+// and the level "off" is never logged.
+func levelEnabled(threshold, level string) bool {
+	msgRank, ok := logLevels[strings.ToLower(level)]
+	if !ok {
+		return false
+	}
+	minRank, ok := logLevels[strings.ToLower(threshold)]
+	if !ok {
+		minRank = logLevels["info"]
+	}
+	return msgRank >= minRank
+}
+
+// mcpLogLevels maps the syslog levels of MCP log messages to logging levels
+var mcpLogLevels = map[string]string{
+	"debug":     "debug",
+	"info":      "info",
+	"notice":    "info",
+	"warning":   "warn",
+	"error":     "error",
+	"critical":  "error",
+	"alert":     "error",
+	"emergency": "error",
+}
+
+// watchLogs logs the messages a server sends with notifications/message at
+// the matching level
+func (b *Bridge) watchLogs(serverCfg config.MCPServerConfig, client *MCPClient) {
+	ignore := compilePatterns(serverCfg.LogIgnore)
+
+	client.OnNotification("notifications/message", func(params json.RawMessage) {
+		var msg struct {
+			Level  string          `json:"level"`
+			Logger string          `json:"logger"`
+			Data   json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(params, &msg); err != nil {
+			b.logger.Printf("Invalid log message from %s: %v", serverCfg.Name, err)
+			return
+		}
+
+		level, ok := mcpLogLevels[msg.Level]
+		if !ok {
+			level = "info"
+		}
+		if !levelEnabled(b.config.Logging.Level, level) {
+			return
+		}
+
+		// Data may be any JSON value; strings are logged without their quotes
+		text := string(msg.Data)
+		var str string
+		if err := json.Unmarshal(msg.Data, &str); err == nil {
+			text = str
+		}
+		if msg.Logger != "" {
+			text = msg.Logger + ": " + text
+		}
+		if matchesAny(ignore, text) {
+			return
+		}
+		b.logger.Printf("[%s] [%s] %s", serverCfg.Name, level, text)
+	})
+}
+
+// applyLogLevel asks an initialized server for its configured log level
+func (b *Bridge) applyLogLevel(serverCfg config.MCPServerConfig, client *MCPClient) {
+	caps := client.Capabilities()
+	if serverCfg.LogLevel == "" || caps.Logging == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, serverCfg.RequestTimeout())
+	defer cancel()
+	if err := client.SetLogLevel(ctx, serverCfg.LogLevel); err != nil {
+		b.logger.Printf("Failed to set log level of %s to %s: %v", serverCfg.Name, serverCfg.LogLevel, err)
+	}
+}
+
+// compilePatterns compiles the ignore patterns of a server, skipping invalid ones
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			compiled = append(compiled, re)
+		}
+	}
+	return compiled
+}
+
+// matchesAny reports whether s matches one of the patterns
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineBuffer keeps the most recent lines written to it
+type lineBuffer struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+// newLineBuffer creates a buffer holding up to size lines
+func newLineBuffer(size int) *lineBuffer {
+	return &lineBuffer{lines: make([]string, size)}
+}
+
+// Add appends a line, dropping the oldest once the buffer is full
+func (b *lineBuffer) Add(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.lines) == 0 {
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Last returns up to n of the most recent lines, oldest first. A
+// non-positive n returns every line held.
+func (b *lineBuffer) Last(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []string
+	if b.full {
+		lines = append(lines, b.lines[b.next:]...)
+	}
+	lines = append(lines, b.lines[:b.next]...)
+
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
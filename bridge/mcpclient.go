@@ -1,408 +1,764 @@
 package bridge
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"os"
-	"os/exec"
+	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sammcj/gomcp/config"
+	"github.com/sammcj/gomcp/types"
 )
 
+// MCPClient speaks the MCP protocol to a single server over a Transport
 type MCPClient struct {
-	cmd      *exec.Cmd
-	stdin    io.WriteCloser
-	stdout   io.ReadCloser
-	logger   *log.Logger
-	mu       sync.Mutex
-	nextID   int64
-	respChan chan []byte
+	transport Transport
+	logger    *log.Logger
+	nextID    atomic.Int64
+
+	// pending maps in-flight request IDs to the channel awaiting their response
+	pendingMu sync.Mutex
+	pending   map[int64]chan []byte
+	closed    bool
+
+	handlersMu           sync.RWMutex
+	notificationHandlers map[string][]NotificationHandler
+	requestHandlers      map[string]RequestHandler
+	clientCapabilities   map[string]interface{}
+
+	// incoming maps the IDs of requests from the server to the cancel
+	// functions of the handlers working on them
+	incomingMu sync.Mutex
+	incoming   map[string]context.CancelFunc
+
+	// ctx ends when the client is closed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	done chan struct{} // closed once the connection to the server has ended
+
+	// progress maps the progress tokens of in-flight requests to their handlers
+	progressMu sync.Mutex
+	progress   map[string]ProgressHandler
+	nextToken  atomic.Int64
+
+	ignore []*regexp.Regexp // server output not worth logging
+
+	// Negotiated during Initialize
+	stateMu         sync.RWMutex
+	protocolVersion string
+	serverInfo      mcp.Implementation
+	capabilities    mcp.ServerCapabilities
+	instructions    string
 }
 
-// isDevelopmentModeWarning checks if a message is a development mode warning
-func isDevelopmentModeWarning(msg string) bool {
-	return strings.Contains(msg, "Running in development mode")
-}
+// NotificationHandler processes a notification from the server. Handlers run
+// on the goroutine reading server messages, so they must not wait on requests
+// to the same server; start a goroutine for that instead.
+type NotificationHandler func(params json.RawMessage)
 
-func NewMCPClient(command string, args []string, logger *log.Logger) (*MCPClient, error) {
-	logger.Printf("Creating new MCP client with command: %s %v", command, args)
+// RequestHandler answers a request from the server. Each request runs on its
+// own goroutine, and ctx ends if the server cancels the request or the client
+// closes. Returning a *types.RPCError sends that error code to the server.
+type RequestHandler func(ctx context.Context, params json.RawMessage) (interface{}, error)
 
-	// Create a temporary file for stderr output
-	stderrFile, err := os.CreateTemp("", "mcp-stderr-*.log")
-	if err != nil {
-		logger.Printf("Failed to create stderr file: %v", err)
-		return nil, fmt.Errorf("failed to create stderr file: %w", err)
-	}
-	defer stderrFile.Close()
+// latestProtocolVersion is the MCP revision the client offers in its initialize request
+const latestProtocolVersion = "2025-03-26"
 
-	cmd := exec.Command(command, args...)
-	cmd.Stderr = stderrFile
+// supportedProtocolVersions lists the MCP revisions the client can speak, newest first
+var supportedProtocolVersions = []string{latestProtocolVersion, "2024-11-05"}
 
-	// Set up environment
-	env := os.Environ()
-	cmd.Env = append(env,
-		"RUST_LOG=debug",
-		"RUST_BACKTRACE=1",
-		"PYTHONUNBUFFERED=1",
-	)
+// NewMCPClient connects to the server described by cfg, launching it first
+// for stdio servers. Cancelling ctx abandons connecting; it doesn't affect a
+// client that was returned. logLevel is the bridge's logging level, against
+// which the server's stderr level is checked.
+func NewMCPClient(ctx context.Context, cfg config.MCPServerConfig, logger *log.Logger, logLevel string) (*MCPClient, error) {
+	logger.Printf("Creating new MCP client for server: %s", cfg.Name)
 
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		logger.Printf("Failed to create stdin pipe: %v", err)
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
-	}
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		logger.Printf("Failed to create stdout pipe: %v", err)
-		stdin.Close()
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	transport := newTransport(cfg, logger, logLevel)
+	if err := transport.Start(ctx); err != nil {
+		return nil, err
 	}
 
-	logger.Printf("Starting MCP server process...")
-	if err := cmd.Start(); err != nil {
-		logger.Printf("Failed to start MCP server: %v", err)
-		stdin.Close()
-		stdout.Close()
-		return nil, fmt.Errorf("failed to start command: %w", err)
-	}
+	client := newMCPClient(transport, logger)
+	client.ignore = compilePatterns(cfg.LogIgnore)
+	return client, nil
+}
 
+// newMCPClient wraps a started transport
+func newMCPClient(transport Transport, logger *log.Logger) *MCPClient {
+	ctx, cancel := context.WithCancel(context.Background())
 	client := &MCPClient{
-		cmd:      cmd,
-		stdin:    stdin,
-		stdout:   stdout,
-		logger:   logger,
-		nextID:   1,
-		respChan: make(chan []byte, 1),
+		transport:            transport,
+		logger:               logger,
+		pending:              make(map[int64]chan []byte),
+		notificationHandlers: make(map[string][]NotificationHandler),
+		requestHandlers:      make(map[string]RequestHandler),
+		clientCapabilities:   make(map[string]interface{}),
+		incoming:             make(map[string]context.CancelFunc),
+		ctx:                  ctx,
+		cancel:               cancel,
+		done:                 make(chan struct{}),
+		progress:             make(map[string]ProgressHandler),
+	}
+	client.OnNotification("notifications/cancelled", client.handleCancelled)
+	client.OnNotification("notifications/progress", client.handleProgress)
+	client.OnRequest("ping", func(context.Context, json.RawMessage) (interface{}, error) {
+		return map[string]interface{}{}, nil
+	})
+	if rt, ok := transport.(reconnectingTransport); ok {
+		rt.SetReconnectHandler(client.handleReconnect)
 	}
 
 	// Start reading responses in a goroutine
 	go client.readResponses()
+	return client
+}
 
-	// Wait for server to start and check stderr for any startup errors
-	time.Sleep(2 * time.Second)
-	stderrFile.Seek(0, 0)
-	stderrBytes, err := io.ReadAll(stderrFile)
-	if err != nil {
-		logger.Printf("Failed to read stderr: %v", err)
-	} else if len(stderrBytes) > 0 {
-		// Only log stderr if it's not a development mode warning
-		if !isDevelopmentModeWarning(string(stderrBytes)) {
-			logger.Printf("MCP server stderr output: %s", string(stderrBytes))
+func (c *MCPClient) readResponses() {
+	for data := range c.transport.Messages() {
+		for _, line := range splitBatch(data) {
+			c.handleMessage(line)
 		}
 	}
+	c.failPending()
+	close(c.done)
+}
 
-	// Send a test request to verify server is working
-	testReq := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      client.nextID,
-		"method":  "tools/list",
-		"params":  map[string]interface{}{},
+// Done returns a channel that is closed once the connection to the server has
+// ended, whether the server exited or the client was closed
+func (c *MCPClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// ExitStatus reports how a locally launched server exited. It returns false
+// for remote servers, and blocks until a local server has exited.
+func (c *MCPClient) ExitStatus() (code int, stderrTail string, ok bool) {
+	reporter, ok := c.transport.(exitReporter)
+	if !ok {
+		return 0, "", false
 	}
-	client.nextID++
+	code, stderrTail = reporter.ExitStatus()
+	return code, stderrTail, true
+}
 
-	if err := client.sendRequest(testReq); err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to send test request: %w", err)
+// splitBatch returns the messages in a JSON-RPC batch, or the message itself if it isn't one
+func splitBatch(data []byte) [][]byte {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return [][]byte{data}
 	}
 
-	// Wait for test response
-	select {
-	case respBytes, ok := <-client.respChan:
-		if !ok {
-			client.Close()
-			return nil, fmt.Errorf("response channel closed during test")
+	var batch []json.RawMessage
+	if err := json.Unmarshal(trimmed, &batch); err != nil {
+		return nil
+	}
+	messages := make([][]byte, len(batch))
+	for i, msg := range batch {
+		messages[i] = msg
+	}
+	return messages
+}
+
+// handleMessage dispatches a single message received from the server
+func (c *MCPClient) handleMessage(line []byte) {
+	// Try to parse as JSON
+	var msg map[string]interface{}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		// Skip non-JSON lines (e.g., pnpm startup messages)
+		return
+	}
+
+	// Handle notifications separately
+	if _, hasMethod := msg["method"]; hasMethod {
+		if method, ok := msg["method"].(string); ok && method == "notify" {
+			if params, ok := msg["params"].(map[string]interface{}); ok {
+				// Only log notifications that aren't configured to be ignored
+				if !matchesAny(c.ignore, fmt.Sprintf("%v", params)) {
+					c.logger.Printf("Notification received: %+v", params)
+				}
+			}
+			return
 		}
-		var resp map[string]interface{}
-		if err := json.Unmarshal(respBytes, &resp); err != nil {
-			client.Close()
-			return nil, fmt.Errorf("failed to unmarshal test response: %w", err)
+		if method, ok := msg["method"].(string); ok {
+			if _, hasID := msg["id"]; !hasID {
+				c.handleNotification(method, line)
+			} else {
+				c.handleRequest(method, line)
+			}
+			return
 		}
-		logger.Printf("Test response received: %+v", resp)
+	}
 
-	case <-time.After(5 * time.Second):
-		client.Close()
-		return nil, fmt.Errorf("timeout waiting for test response")
+	// Only log and forward actual responses
+	if _, hasResult := msg["result"]; hasResult {
+		c.logger.Printf("Response received: %s", string(line))
+		c.deliver(msg["id"], line)
+		return
 	}
 
-	logger.Printf("MCP server process started with PID: %d", cmd.Process.Pid)
-	return client, nil
+	// Error responses go to the waiting caller as well
+	if _, hasError := msg["error"]; hasError {
+		c.logger.Printf("Error response received: %s", string(line))
+		c.deliver(msg["id"], line)
+	}
 }
 
-func (c *MCPClient) readResponses() {
-	reader := bufio.NewReader(c.stdout)
-	for {
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			if err != io.EOF {
-				c.logger.Printf("Error reading response: %v", err)
-			}
-			close(c.respChan)
+// OnNotification registers a handler for notifications with the given method
+func (c *MCPClient) OnNotification(method string, handler NotificationHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.notificationHandlers[method] = append(c.notificationHandlers[method], handler)
+}
+
+// handleNotification passes a notification to the handlers registered for its method
+func (c *MCPClient) handleNotification(method string, line []byte) {
+	var notification struct {
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(line, &notification); err != nil {
+		c.logger.Printf("Failed to unmarshal notification: %v", err)
+		return
+	}
+
+	c.handlersMu.RLock()
+	handlers := c.notificationHandlers[method]
+	c.handlersMu.RUnlock()
+
+	if len(handlers) == 0 {
+		c.logger.Printf("Unhandled notification: %s", method)
+		return
+	}
+	for _, handler := range handlers {
+		handler(notification.Params)
+	}
+}
+
+// OnRequest registers the handler for requests from the server with the given method
+func (c *MCPClient) OnRequest(method string, handler RequestHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.requestHandlers[method] = handler
+}
+
+// SetCapability declares a client capability to advertise in the initialize request
+func (c *MCPClient) SetCapability(name string, value interface{}) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+	c.clientCapabilities[name] = value
+}
+
+// handleRequest runs the handler for a request from the server and sends its
+// result or error back
+func (c *MCPClient) handleRequest(method string, line []byte) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(line, &req); err != nil {
+		c.logger.Printf("Failed to unmarshal request: %v", err)
+		return
+	}
+	c.logger.Printf("Request received: %s", string(line))
+
+	c.handlersMu.RLock()
+	handler, ok := c.requestHandlers[method]
+	c.handlersMu.RUnlock()
+
+	if !ok {
+		c.respond(req.ID, nil, &types.RPCError{Code: -32601, Message: fmt.Sprintf("method not found: %s", method)})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	key := string(req.ID)
+	c.incomingMu.Lock()
+	c.incoming[key] = cancel
+	c.incomingMu.Unlock()
+
+	go func() {
+		defer func() {
+			c.incomingMu.Lock()
+			delete(c.incoming, key)
+			c.incomingMu.Unlock()
+			cancel()
+		}()
+
+		result, err := handler(ctx, req.Params)
+		if ctx.Err() != nil {
+			// The server cancelled the request and expects no response
 			return
 		}
+		c.respond(req.ID, result, err)
+	}()
+}
 
-		// Skip empty lines
-		if len(line) == 0 {
-			continue
-		}
+// handleCancelled stops the handler of a request the server no longer wants answered
+func (c *MCPClient) handleCancelled(params json.RawMessage) {
+	var cancelled struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	if err := json.Unmarshal(params, &cancelled); err != nil {
+		return
+	}
 
-		// Try to parse as JSON
-		var msg map[string]interface{}
-		if err := json.Unmarshal(line, &msg); err != nil {
-			// Skip non-JSON lines (e.g., pnpm startup messages)
-			continue
-		}
+	c.incomingMu.Lock()
+	cancel, ok := c.incoming[string(cancelled.RequestID)]
+	c.incomingMu.Unlock()
+	if ok {
+		cancel()
+	}
+}
 
-		// Handle notifications separately
-		if _, hasMethod := msg["method"]; hasMethod {
-			if method, ok := msg["method"].(string); ok && method == "notify" {
-				if params, ok := msg["params"].(map[string]interface{}); ok {
-					// Only log notifications that aren't development mode warnings
-					if !isDevelopmentModeWarning(fmt.Sprintf("%v", params)) {
-						c.logger.Printf("Notification received: %+v", params)
-					}
-				}
-				continue
-			}
+// respond sends the response to a request from the server
+func (c *MCPClient) respond(id json.RawMessage, result interface{}, err error) {
+	resp := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+	}
+	if err != nil {
+		var rpcErr *types.RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &types.RPCError{Code: -32603, Message: err.Error()}
 		}
-
-		// Only log and forward actual responses
-		if _, hasResult := msg["result"]; hasResult {
-			c.logger.Printf("Response received: %s", string(line))
-			c.respChan <- line
+		resp["error"] = rpcErr
+	} else {
+		if result == nil {
+			result = map[string]interface{}{}
 		}
+		resp["result"] = result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.sendRequest(ctx, resp); err != nil {
+		c.logger.Printf("Failed to send response: %v", err)
 	}
 }
 
-func (c *MCPClient) Initialize(ctx context.Context, req mcp.InitializeRequest) (*mcp.InitializeResult, error) {
-	c.logger.Printf("Sending initialize request: %+v", req)
+// deliver routes a response to the caller waiting on its request ID
+func (c *MCPClient) deliver(rawID interface{}, line []byte) {
+	id, ok := rawID.(float64)
+	if !ok {
+		c.logger.Printf("Dropping response with unexpected id: %v", rawID)
+		return
+	}
+
+	c.pendingMu.Lock()
+	ch, ok := c.pending[int64(id)]
+	delete(c.pending, int64(id))
+	c.pendingMu.Unlock()
+
+	if !ok {
+		c.logger.Printf("Dropping response for unknown request id: %d", int64(id))
+		return
+	}
+	ch <- line
+}
+
+// failPending closes the channel of every in-flight request once the server stops responding
+func (c *MCPClient) failPending() {
+	c.pendingMu.Lock()
+	c.closed = true
+	c.pendingMu.Unlock()
+
+	c.abortPending()
+}
+
+// abortPending closes the channel of every in-flight request
+func (c *MCPClient) abortPending() {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+// handleReconnect runs after the transport re-established a dropped connection.
+// The server sees a new session, so requests sent on the old one will never be
+// answered and the initialize handshake has to be repeated.
+func (c *MCPClient) handleReconnect() {
+	c.abortPending()
+
+	c.stateMu.RLock()
+	initialized := c.protocolVersion != ""
+	c.stateMu.RUnlock()
+	if !initialized {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.DefaultRequestTimeout)
+	defer cancel()
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		c.logger.Printf("Failed to re-initialize after reconnect: %v", err)
+	}
+}
+
+// call sends a JSON-RPC request and waits for the response carrying the same ID.
+// A JSON-RPC error response is returned as a *types.RPCError. If ctx ends first the
+// server is sent notifications/cancelled so it can stop working on the request,
+// except for initialize, which the protocol does not allow to be cancelled.
+func (c *MCPClient) call(ctx context.Context, method string, params interface{}) ([]byte, error) {
+	id := c.nextID.Add(1)
+	ch := make(chan []byte, 1)
+
+	c.pendingMu.Lock()
+	if c.closed {
+		c.pendingMu.Unlock()
+		return nil, fmt.Errorf("response channel closed")
+	}
+	c.pending[id] = ch
+	c.pendingMu.Unlock()
 
-	// Create JSON-RPC request with correct method name
 	rpcReq := map[string]interface{}{
 		"jsonrpc": "2.0",
-		"id":      c.nextID,
-		"method":  "initialize",
-		"params": map[string]interface{}{
-			"protocolVersion": "1.0.0",
-			"capabilities": map[string]interface{}{
-				"experimental": map[string]interface{}{},
-			},
-			"clientInfo": map[string]interface{}{
-				"name":    "gomcp",
-				"version": "0.1.0",
-			},
-		},
+		"id":      id,
+		"method":  method,
+		"params":  params,
 	}
-	c.nextID++
-
-	// Send request
-	if err := c.sendRequest(rpcReq); err != nil {
-		c.logger.Printf("Failed to send initialize request: %v", err)
+	if err := c.sendRequest(ctx, rpcReq); err != nil {
+		c.forget(id)
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	// Read response with timeout
 	select {
-	case respBytes, ok := <-c.respChan:
+	case respBytes, ok := <-ch:
 		if !ok {
 			return nil, fmt.Errorf("response channel closed")
 		}
+		var envelope struct {
+			Error *types.RPCError `json:"error"`
+		}
+		if err := json.Unmarshal(respBytes, &envelope); err == nil && envelope.Error != nil {
+			return nil, envelope.Error
+		}
+		return respBytes, nil
+	case <-ctx.Done():
+		c.forget(id)
+		if method != "initialize" {
+			c.cancelRequest(id, ctx.Err())
+		}
+		return nil, fmt.Errorf("%s request abandoned: %w", method, ctx.Err())
+	}
+}
+
+// cancelRequest tells the server that the client is no longer waiting for a request
+func (c *MCPClient) cancelRequest(id int64, reason error) {
+	params := map[string]interface{}{
+		"requestId": id,
+		"reason":    reason.Error(),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.sendNotification(ctx, "notifications/cancelled", params); err != nil {
+		c.logger.Printf("Failed to send cancellation for request %d: %v", id, err)
+	}
+}
+
+// sendNotification sends a JSON-RPC notification, which has no ID and gets no response
+func (c *MCPClient) sendNotification(ctx context.Context, method string, params interface{}) error {
+	notification := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+	}
+	if params != nil {
+		notification["params"] = params
+	}
+	return c.sendRequest(ctx, notification)
+}
+
+// forget removes a request from the pending table when its caller stops waiting
+func (c *MCPClient) forget(id int64) {
+	c.pendingMu.Lock()
+	delete(c.pending, id)
+	c.pendingMu.Unlock()
+}
+
+// Initialize performs the MCP initialize handshake. It must complete before any
+// other request is sent. The server's protocol version must be one the client
+// supports; its info, capabilities and instructions are kept on the client.
+func (c *MCPClient) Initialize(ctx context.Context, req mcp.InitializeRequest) (*mcp.InitializeResult, error) {
+	c.logger.Printf("Sending initialize request: %+v", req)
+
+	c.handlersMu.RLock()
+	capabilities := make(map[string]interface{}, len(c.clientCapabilities))
+	for name, value := range c.clientCapabilities {
+		capabilities[name] = value
+	}
+	c.handlersMu.RUnlock()
+
+	params := map[string]interface{}{
+		"protocolVersion": latestProtocolVersion,
+		"capabilities":    capabilities,
+		"clientInfo": map[string]interface{}{
+			"name":    "gomcp",
+			"version": "0.1.0",
+		},
+	}
+
+	respBytes, err := c.call(ctx, "initialize", params)
+	if err != nil {
+		c.logger.Printf("Initialize request failed: %v", err)
+		return nil, c.withStderr(err)
+	}
+
+	var resp struct {
+		Result mcp.InitializeResult `json:"result"`
+	}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		c.logger.Printf("Failed to unmarshal response: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	result := &resp.Result
+
+	c.logger.Printf("Received initialize response: %+v", result)
 
-		var resp map[string]interface{}
+	if !isSupportedProtocolVersion(result.ProtocolVersion) {
+		return nil, fmt.Errorf("server %s requested unsupported protocol version %q (supported: %s)",
+			result.ServerInfo.Name, result.ProtocolVersion, strings.Join(supportedProtocolVersions, ", "))
+	}
+
+	c.stateMu.Lock()
+	c.protocolVersion = result.ProtocolVersion
+	c.serverInfo = result.ServerInfo
+	c.capabilities = result.Capabilities
+	c.instructions = result.Instructions
+	c.stateMu.Unlock()
+
+	if err := c.sendNotification(ctx, "notifications/initialized", nil); err != nil {
+		c.logger.Printf("Failed to send initialized notification: %v", err)
+		return nil, fmt.Errorf("failed to send initialized notification: %w", err)
+	}
+
+	return result, nil
+}
+
+// SetLogLevel asks the server to send log messages at level and above
+func (c *MCPClient) SetLogLevel(ctx context.Context, level string) error {
+	c.logger.Printf("Setting server log level: %s", level)
+	_, err := c.call(ctx, "logging/setLevel", map[string]interface{}{"level": level})
+	return err
+}
+
+// Ping checks that the server is still responding
+func (c *MCPClient) Ping(ctx context.Context) error {
+	_, err := c.call(ctx, "ping", map[string]interface{}{})
+	return err
+}
+
+// ProtocolVersion returns the MCP revision agreed during Initialize
+func (c *MCPClient) ProtocolVersion() string {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.protocolVersion
+}
+
+// ServerInfo returns the name and version the server reported during Initialize
+func (c *MCPClient) ServerInfo() mcp.Implementation {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.serverInfo
+}
+
+// Capabilities returns the capabilities the server advertised during Initialize
+func (c *MCPClient) Capabilities() mcp.ServerCapabilities {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.capabilities
+}
+
+// Instructions returns the usage hints the server provided during Initialize, if any
+func (c *MCPClient) Instructions() string {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.instructions
+}
+
+// listAll sends a paginated list request, following nextCursor until every
+// page is fetched, and returns the items found under field in each result
+func listAll[T any](ctx context.Context, c *MCPClient, method, field string) ([]T, error) {
+	var items []T
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		respBytes, err := c.call(ctx, method, params)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp struct {
+			Result map[string]json.RawMessage `json:"result"`
+		}
 		if err := json.Unmarshal(respBytes, &resp); err != nil {
-			c.logger.Printf("Failed to unmarshal response: %v", err)
 			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 		}
 
-		c.logger.Printf("Received initialize response: %+v", resp)
-
-		// Parse response
-		result := &mcp.InitializeResult{}
-		if resultData, ok := resp["result"].(map[string]interface{}); ok {
-			if capabilities, ok := resultData["capabilities"].(map[string]interface{}); ok {
-				if exp, ok := capabilities["experimental"].(map[string]interface{}); ok {
-					result.Capabilities.Experimental = exp
-				}
+		var page []T
+		if raw, ok := resp.Result[field]; ok {
+			if err := json.Unmarshal(raw, &page); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal %s: %w", field, err)
 			}
 		}
-		return result, nil
+		items = append(items, page...)
 
-	case <-time.After(10 * time.Second):
-		c.logger.Printf("Timeout waiting for initialize response")
-		return nil, fmt.Errorf("timeout waiting for response")
+		var next string
+		if raw, ok := resp.Result["nextCursor"]; ok {
+			json.Unmarshal(raw, &next)
+		}
+		if next == "" || next == cursor {
+			return items, nil
+		}
+		cursor = next
 	}
 }
 
+// isSupportedProtocolVersion reports whether the client can speak the given MCP revision
+func isSupportedProtocolVersion(version string) bool {
+	for _, supported := range supportedProtocolVersions {
+		if version == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ListTools returns every tool the server offers, following pagination
 func (c *MCPClient) ListTools(ctx context.Context, req mcp.ListToolsRequest) (*mcp.ListToolsResult, error) {
 	c.logger.Printf("Sending list tools request")
 
-	// Create JSON-RPC request
-	rpcReq := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      c.nextID,
-		"method":  "tools/list",
-		"params":  map[string]interface{}{},
+	// Schemas are decoded separately so one malformed tool doesn't hide the rest
+	type rawTool struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		InputSchema json.RawMessage `json:"inputSchema"`
 	}
-	c.nextID++
-
-	// Send request
-	if err := c.sendRequest(rpcReq); err != nil {
-		c.logger.Printf("Failed to send list tools request: %v", err)
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	tools, err := listAll[rawTool](ctx, c, "tools/list", "tools")
+	if err != nil {
+		c.logger.Printf("List tools request failed: %v", err)
+		return nil, err
 	}
 
-	// Read response with timeout
-	select {
-	case respBytes, ok := <-c.respChan:
-		if !ok {
-			return nil, fmt.Errorf("response channel closed")
-		}
+	c.logger.Printf("Received %d tools", len(tools))
 
-		var resp map[string]interface{}
-		if err := json.Unmarshal(respBytes, &resp); err != nil {
-			c.logger.Printf("Failed to unmarshal response: %v", err)
-			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-		}
-
-		c.logger.Printf("Received list tools response: %+v", resp)
-
-		// Parse response
-		result := &mcp.ListToolsResult{}
-		if resultData, ok := resp["result"].(map[string]interface{}); ok {
-			if tools, ok := resultData["tools"].([]interface{}); ok {
-				for _, tool := range tools {
-					if toolMap, ok := tool.(map[string]interface{}); ok {
-						name, _ := toolMap["name"].(string)
-						desc, _ := toolMap["description"].(string)
-
-						// Convert schema to ToolInputSchema
-						var schema mcp.ToolInputSchema
-						if schemaMap, ok := toolMap["inputSchema"].(map[string]interface{}); ok {
-							schemaBytes, err := json.Marshal(schemaMap)
-							if err != nil {
-								c.logger.Printf("Failed to marshal schema: %v", err)
-								continue
-							}
-							if err := json.Unmarshal(schemaBytes, &schema); err != nil {
-								c.logger.Printf("Failed to unmarshal schema: %v", err)
-								continue
-							}
-						}
-
-						result.Tools = append(result.Tools, mcp.Tool{
-							Name:        name,
-							Description: desc,
-							InputSchema: schema,
-						})
-					}
-				}
+	result := &mcp.ListToolsResult{}
+	for _, tool := range tools {
+		var schema mcp.ToolInputSchema
+		if len(tool.InputSchema) > 0 {
+			if err := json.Unmarshal(tool.InputSchema, &schema); err != nil {
+				c.logger.Printf("Failed to unmarshal schema for tool %s: %v", tool.Name, err)
+				continue
 			}
 		}
-		return result, nil
 
-	case <-time.After(10 * time.Second):
-		c.logger.Printf("Timeout waiting for list tools response")
-		return nil, fmt.Errorf("timeout waiting for response")
+		result.Tools = append(result.Tools, mcp.Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		})
 	}
+	return result, nil
 }
 
 func (c *MCPClient) CallTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	c.logger.Printf("Sending call tool request: %+v", req)
 
-	// Create JSON-RPC request
-	rpcReq := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      c.nextID,
-		"method":  "tools/call",
-		"params": map[string]interface{}{
-			"name":      req.Params.Name,
-			"arguments": req.Params.Arguments,
-		},
+	params := map[string]interface{}{
+		"name":      req.Params.Name,
+		"arguments": req.Params.Arguments,
 	}
-	c.nextID++
 
-	// Send request
-	if err := c.sendRequest(rpcReq); err != nil {
-		c.logger.Printf("Failed to send call tool request: %v", err)
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	// Ask the server for progress updates if the caller wants them
+	if handler := progressHandler(ctx); handler != nil {
+		token, stop := c.trackProgress(handler)
+		defer stop()
+		params["_meta"] = map[string]interface{}{"progressToken": token}
 	}
 
-	// Read response with timeout
-	select {
-	case respBytes, ok := <-c.respChan:
-		if !ok {
-			return nil, fmt.Errorf("response channel closed")
+	respBytes, err := c.call(ctx, "tools/call", params)
+	if err != nil {
+		c.logger.Printf("Call tool request failed: %v", err)
+		var rpcErr *types.RPCError
+		if errors.As(err, &rpcErr) {
+			return nil, &types.ToolError{
+				Tool:    req.Params.Name,
+				Message: "server rejected the call",
+				Err:     rpcErr,
+			}
 		}
+		return nil, c.withStderr(err)
+	}
 
-		var resp map[string]interface{}
-		if err := json.Unmarshal(respBytes, &resp); err != nil {
-			c.logger.Printf("Failed to unmarshal response: %v", err)
-			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-		}
+	var resp struct {
+		Result struct {
+			Content []json.RawMessage `json:"content"`
+			IsError bool              `json:"isError"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		c.logger.Printf("Failed to unmarshal response: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
 
-		c.logger.Printf("Received call tool response: %+v", resp)
-
-		// Parse response
-		result := &mcp.CallToolResult{}
-		if resultData, ok := resp["result"].(map[string]interface{}); ok {
-			if content, ok := resultData["content"].([]interface{}); ok {
-				for _, item := range content {
-					if textContent, ok := item.(map[string]interface{}); ok {
-						if text, ok := textContent["text"].(string); ok {
-							result.Content = append(result.Content, mcp.TextContent{
-								Text: text,
-							})
-						}
-					}
-				}
-			}
-		}
-		return result, nil
+	c.logger.Printf("Received call tool response: %s", string(respBytes))
 
-	case <-time.After(10 * time.Second):
-		c.logger.Printf("Timeout waiting for call tool response")
-		return nil, fmt.Errorf("timeout waiting for response")
+	// Parse response
+	result := &mcp.CallToolResult{IsError: resp.Result.IsError}
+	for _, raw := range resp.Result.Content {
+		content, err := decodeContent(raw)
+		if err != nil {
+			c.logger.Printf("Skipping content from %s: %v", req.Params.Name, err)
+			continue
+		}
+		result.Content = append(result.Content, content)
 	}
+	return result, nil
 }
 
-func (c *MCPClient) Close() error {
-	c.logger.Println("Closing MCP client...")
+// errorStderrLines is how many recent stderr lines are attached to errors
+const errorStderrLines = 10
 
-	if err := c.stdin.Close(); err != nil {
-		c.logger.Printf("Failed to close stdin: %v", err)
-		return fmt.Errorf("failed to close stdin: %w", err)
+// withStderr attaches the server's most recent stderr output to err, since
+// it usually explains why a local server failed
+func (c *MCPClient) withStderr(err error) error {
+	reporter, ok := c.transport.(stderrReporter)
+	if !ok {
+		return err
 	}
-
-	if err := c.stdout.Close(); err != nil {
-		c.logger.Printf("Failed to close stdout: %v", err)
-		return fmt.Errorf("failed to close stdout: %w", err)
+	lines := reporter.RecentStderr(errorStderrLines)
+	if len(lines) == 0 {
+		return err
 	}
+	return fmt.Errorf("%w\nrecent server stderr:\n%s", err, strings.Join(lines, "\n"))
+}
 
-	if err := c.cmd.Process.Kill(); err != nil {
-		c.logger.Printf("Failed to kill process: %v", err)
-		return fmt.Errorf("failed to kill process: %w", err)
-	}
+// Close disconnects from the server, stopping it if it was launched locally
+func (c *MCPClient) Close() error {
+	c.logger.Println("Closing MCP client...")
+	c.cancel()
 
-	if err := c.cmd.Wait(); err != nil {
-		c.logger.Printf("Failed to wait for process: %v", err)
-		return fmt.Errorf("failed to wait for process: %w", err)
+	if err := c.transport.Close(); err != nil {
+		return err
 	}
 
 	c.logger.Println("MCP client closed successfully")
 	return nil
 }
 
-func (c *MCPClient) sendRequest(req interface{}) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
+func (c *MCPClient) sendRequest(ctx context.Context, req interface{}) error {
 	data, err := json.Marshal(req)
 	if err != nil {
 		c.logger.Printf("Failed to marshal request: %v", err)
@@ -411,9 +767,9 @@ func (c *MCPClient) sendRequest(req interface{}) error {
 
 	c.logger.Printf("Sending raw request: %s", string(data))
 
-	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+	if err := c.transport.Send(ctx, data); err != nil {
 		c.logger.Printf("Failed to write request: %v", err)
-		return fmt.Errorf("failed to write request: %w", err)
+		return err
 	}
 
 	return nil
@@ -0,0 +1,292 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sammcj/gomcp/types"
+)
+
+// testLogger discards the bridge's logging during tests
+var testLogger = log.New(io.Discard, "", 0)
+
+// fakeTransport hands the messages a client sends to the test, which answers
+// by pushing messages as if they came from the server
+type fakeTransport struct {
+	sent      chan []byte
+	messages  chan []byte
+	closeOnce sync.Once
+}
+
+func newFakeTransport() *fakeTransport {
+	return &fakeTransport{
+		sent:     make(chan []byte, 16),
+		messages: make(chan []byte, 16),
+	}
+}
+
+func (f *fakeTransport) Start(ctx context.Context) error { return nil }
+
+func (f *fakeTransport) Send(ctx context.Context, msg []byte) error {
+	select {
+	case f.sent <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *fakeTransport) Messages() <-chan []byte { return f.messages }
+
+func (f *fakeTransport) Close() error {
+	f.closeOnce.Do(func() { close(f.messages) })
+	return nil
+}
+
+// sentMessage is a message the client sent, as the server sees it
+type sentMessage struct {
+	ID     json.RawMessage        `json:"id"`
+	Method string                 `json:"method"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// nextSent waits for the next message the client sends
+func (f *fakeTransport) nextSent(t *testing.T) sentMessage {
+	t.Helper()
+	select {
+	case data := <-f.sent:
+		var msg sentMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("client sent invalid JSON %s: %v", data, err)
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("client sent nothing")
+		return sentMessage{}
+	}
+}
+
+// reply sends a message from the server to the client
+func (f *fakeTransport) reply(format string, args ...interface{}) {
+	f.messages <- []byte(fmt.Sprintf(format, args...))
+}
+
+// callResult is what a call returned
+type callResult struct {
+	resp []byte
+	err  error
+}
+
+// startCall runs a call in the background
+func startCall(ctx context.Context, client *MCPClient, method string, params interface{}) <-chan callResult {
+	done := make(chan callResult, 1)
+	go func() {
+		resp, err := client.call(ctx, method, params)
+		done <- callResult{resp, err}
+	}()
+	return done
+}
+
+// waitCall waits for a call started by startCall to return
+func waitCall(t *testing.T, done <-chan callResult) callResult {
+	t.Helper()
+	select {
+	case result := <-done:
+		return result
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not return")
+		return callResult{}
+	}
+}
+
+func TestMCPClientCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string // the server's answer, with %s for the request ID
+		want     string // the result the call returns
+		wantErr  *types.RPCError
+	}{
+		{
+			name:     "result",
+			response: `{"jsonrpc":"2.0","id":%s,"result":{"ok":true}}`,
+			want:     `{"ok":true}`,
+		},
+		{
+			name:     "result in a batch",
+			response: `[{"jsonrpc":"2.0","method":"notifications/message","params":{}},{"jsonrpc":"2.0","id":%s,"result":{"ok":true}}]`,
+			want:     `{"ok":true}`,
+		},
+		{
+			name:     "error",
+			response: `{"jsonrpc":"2.0","id":%s,"error":{"code":-32601,"message":"method not found"}}`,
+			wantErr:  &types.RPCError{Code: -32601, Message: "method not found"},
+		},
+		{
+			name:     "error with data",
+			response: `{"jsonrpc":"2.0","id":%s,"error":{"code":-32602,"message":"invalid params","data":"limit"}}`,
+			wantErr:  &types.RPCError{Code: -32602, Message: "invalid params", Data: "limit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := newFakeTransport()
+			client := newMCPClient(transport, testLogger)
+			defer client.Close()
+
+			done := startCall(context.Background(), client, "tools/list", nil)
+			req := transport.nextSent(t)
+			if req.Method != "tools/list" {
+				t.Fatalf("sent method %q, want tools/list", req.Method)
+			}
+			transport.reply(tt.response, req.ID)
+			result := waitCall(t, done)
+
+			if tt.wantErr != nil {
+				var rpcErr *types.RPCError
+				if !errors.As(result.err, &rpcErr) {
+					t.Fatalf("error = %v, want a JSON-RPC error", result.err)
+				}
+				if *rpcErr != *tt.wantErr {
+					t.Errorf("error = %+v, want %+v", rpcErr, tt.wantErr)
+				}
+				if !errors.Is(result.err, types.ErrServerRejected) {
+					t.Errorf("error %v does not match ErrServerRejected", result.err)
+				}
+				return
+			}
+			if result.err != nil {
+				t.Fatalf("call: %v", result.err)
+			}
+			var envelope struct {
+				Result json.RawMessage `json:"result"`
+			}
+			if err := json.Unmarshal(result.resp, &envelope); err != nil {
+				t.Fatalf("invalid response %s: %v", result.resp, err)
+			}
+			if string(envelope.Result) != tt.want {
+				t.Errorf("result = %s, want %s", envelope.Result, tt.want)
+			}
+		})
+	}
+}
+
+func TestMCPClientConcurrentCalls(t *testing.T) {
+	transport := newFakeTransport()
+	client := newMCPClient(transport, testLogger)
+	defer client.Close()
+
+	first := startCall(context.Background(), client, "first", nil)
+	firstReq := transport.nextSent(t)
+	second := startCall(context.Background(), client, "second", nil)
+	secondReq := transport.nextSent(t)
+
+	// The server answers the later request first
+	transport.reply(`{"jsonrpc":"2.0","id":%s,"result":{"method":"second"}}`, secondReq.ID)
+	transport.reply(`{"jsonrpc":"2.0","id":%s,"result":{"method":"first"}}`, firstReq.ID)
+
+	for method, done := range map[string]<-chan callResult{"first": first, "second": second} {
+		result := waitCall(t, done)
+		if result.err != nil {
+			t.Fatalf("%s call: %v", method, result.err)
+		}
+		var envelope struct {
+			Result struct {
+				Method string `json:"method"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(result.resp, &envelope); err != nil {
+			t.Fatalf("invalid response %s: %v", result.resp, err)
+		}
+		if envelope.Result.Method != method {
+			t.Errorf("%s call got the response to %s", method, envelope.Result.Method)
+		}
+	}
+}
+
+func TestMCPClientCancelledCall(t *testing.T) {
+	transport := newFakeTransport()
+	client := newMCPClient(transport, testLogger)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := startCall(ctx, client, "tools/call", nil)
+	req := transport.nextSent(t)
+	cancel()
+
+	result := waitCall(t, done)
+	if !errors.Is(result.err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", result.err)
+	}
+
+	// The server is told to stop working on the request
+	notification := transport.nextSent(t)
+	if notification.Method != "notifications/cancelled" {
+		t.Fatalf("sent %q after cancelling, want notifications/cancelled", notification.Method)
+	}
+	if notification.ID != nil {
+		t.Errorf("cancellation was sent with an ID, making it a request")
+	}
+	if got := fmt.Sprint(notification.Params["requestId"]); got != string(req.ID) {
+		t.Errorf("cancelled request %s, want %s", got, req.ID)
+	}
+
+	// The request is forgotten, so a response arriving after all is dropped
+	client.pendingMu.Lock()
+	pending := len(client.pending)
+	client.pendingMu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d requests still pending after cancelling", pending)
+	}
+}
+
+func TestMCPClientCancelledInitialize(t *testing.T) {
+	transport := newFakeTransport()
+	client := newMCPClient(transport, testLogger)
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := startCall(ctx, client, "initialize", nil)
+	transport.nextSent(t)
+	cancel()
+
+	if result := waitCall(t, done); !errors.Is(result.err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", result.err)
+	}
+
+	// initialize must not be cancelled, so the request is only forgotten
+	select {
+	case data := <-transport.sent:
+		t.Errorf("sent %s after abandoning initialize", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+	client.pendingMu.Lock()
+	pending := len(client.pending)
+	client.pendingMu.Unlock()
+	if pending != 0 {
+		t.Errorf("%d requests still pending after cancelling", pending)
+	}
+}
+
+func TestMCPClientCallFailsWhenConnectionEnds(t *testing.T) {
+	transport := newFakeTransport()
+	client := newMCPClient(transport, testLogger)
+
+	done := startCall(context.Background(), client, "tools/list", nil)
+	transport.nextSent(t)
+	transport.Close()
+
+	if result := waitCall(t, done); result.err == nil {
+		t.Error("call returned without error after the connection ended")
+	}
+	if _, err := client.call(context.Background(), "tools/list", nil); err == nil {
+		t.Error("call on a closed connection succeeded")
+	}
+}
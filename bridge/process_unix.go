@@ -0,0 +1,35 @@
+//go:build !windows
+
+package bridge
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup starts the server in a process group of its own, so that
+// wrappers such as sh -c or pnpm can be stopped together with their children
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcessGroup asks every process in the server's group to exit
+func terminateProcessGroup(process *os.Process) error {
+	return signalProcessGroup(process, syscall.SIGTERM)
+}
+
+// killProcessGroup forcibly stops every process in the server's group
+func killProcessGroup(process *os.Process) error {
+	return signalProcessGroup(process, syscall.SIGKILL)
+}
+
+// signalProcessGroup sends sig to the group led by process. A group that no
+// longer exists is not an error.
+func signalProcessGroup(process *os.Process, sig syscall.Signal) error {
+	if err := syscall.Kill(-process.Pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package bridge
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, which has no process groups to signal
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup stops the server process. Windows can't ask a
+// process to exit, so this is the same as killing it.
+func terminateProcessGroup(process *os.Process) error {
+	return killProcessGroup(process)
+}
+
+// killProcessGroup forcibly stops the server process
+func killProcessGroup(process *os.Process) error {
+	if err := process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
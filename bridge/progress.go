@@ -0,0 +1,88 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Progress reports how far a tool call has got, as sent by its server
+type Progress struct {
+	Server   string  `json:"server,omitempty"`
+	Tool     string  `json:"tool,omitempty"`
+	Progress float64 `json:"progress"`
+	Total    float64 `json:"total,omitempty"` // zero when the server doesn't know the total
+	Message  string  `json:"message,omitempty"`
+}
+
+// ProgressHandler receives progress updates. It runs on the goroutine reading
+// server messages, so it must return quickly.
+type ProgressHandler func(Progress)
+
+// progressKey is the context key for the progress handler of a request
+type progressKey struct{}
+
+// WithProgress returns a context whose tool calls report their progress to handler
+func WithProgress(ctx context.Context, handler ProgressHandler) context.Context {
+	return context.WithValue(ctx, progressKey{}, handler)
+}
+
+// progressHandler returns the progress handler of ctx, if it has one
+func progressHandler(ctx context.Context) ProgressHandler {
+	handler, _ := ctx.Value(progressKey{}).(ProgressHandler)
+	return handler
+}
+
+// trackProgress registers handler for the progress of a request and returns
+// the token to send with it, along with a function to stop tracking
+func (c *MCPClient) trackProgress(handler ProgressHandler) (string, func()) {
+	token := fmt.Sprintf("gomcp-%d", c.nextToken.Add(1))
+
+	c.progressMu.Lock()
+	c.progress[token] = handler
+	c.progressMu.Unlock()
+
+	return token, func() {
+		c.progressMu.Lock()
+		delete(c.progress, token)
+		c.progressMu.Unlock()
+	}
+}
+
+// handleProgress passes a notifications/progress update to the handler of its request
+func (c *MCPClient) handleProgress(params json.RawMessage) {
+	var update struct {
+		ProgressToken interface{} `json:"progressToken"`
+		Progress      float64     `json:"progress"`
+		Total         float64     `json:"total"`
+		Message       string      `json:"message"`
+	}
+	if err := json.Unmarshal(params, &update); err != nil {
+		c.logger.Printf("Failed to unmarshal progress notification: %v", err)
+		return
+	}
+
+	c.progressMu.Lock()
+	handler, ok := c.progress[fmt.Sprint(update.ProgressToken)]
+	c.progressMu.Unlock()
+
+	// Updates can arrive after the request they belong to has finished
+	if !ok {
+		return
+	}
+	handler(Progress{Progress: update.Progress, Total: update.Total, Message: update.Message})
+}
+
+// FormatProgress renders a progress update on one line
+func FormatProgress(p Progress) string {
+	line := fmt.Sprintf("%s/%s: ", p.Server, p.Tool)
+	if p.Total > 0 {
+		line += fmt.Sprintf("%.0f%%", p.Progress/p.Total*100)
+	} else {
+		line += fmt.Sprintf("%g", p.Progress)
+	}
+	if p.Message != "" {
+		line += " " + p.Message
+	}
+	return line
+}
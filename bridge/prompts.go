@@ -0,0 +1,297 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sammcj/gomcp/types"
+)
+
+// Prompt describes a prompt template an MCP server makes available
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes an argument a prompt template accepts
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptMessage is a single message of an expanded prompt
+type PromptMessage struct {
+	Role    string        `json:"role"`
+	Content PromptContent `json:"content"`
+}
+
+// PromptContent is the content of a prompt message. Text content sets Text,
+// image content sets Data and MIMEType, and embedded resources set Resource.
+type PromptContent struct {
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Data     string            `json:"data,omitempty"`
+	MIMEType string            `json:"mimeType,omitempty"`
+	Resource *ResourceContents `json:"resource,omitempty"`
+}
+
+// PromptResult is an expanded prompt
+type PromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// ServerPrompt is a prompt together with the server offering it
+type ServerPrompt struct {
+	Server string `json:"server"`
+	Prompt
+}
+
+// ListPrompts returns every prompt the server offers
+func (c *MCPClient) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	c.logger.Printf("Sending list prompts request")
+	return listAll[Prompt](ctx, c, "prompts/list", "prompts")
+}
+
+// GetPrompt expands the named prompt with the given arguments
+func (c *MCPClient) GetPrompt(ctx context.Context, name string, args map[string]string) (*PromptResult, error) {
+	c.logger.Printf("Sending get prompt request: %s", name)
+
+	params := map[string]interface{}{"name": name}
+	if len(args) > 0 {
+		params["arguments"] = args
+	}
+	respBytes, err := c.call(ctx, "prompts/get", params)
+	if err != nil {
+		c.logger.Printf("Get prompt request failed: %v", err)
+		return nil, err
+	}
+
+	var resp struct {
+		Result PromptResult `json:"result"`
+	}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		c.logger.Printf("Failed to unmarshal response: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &resp.Result, nil
+}
+
+// watchPrompts loads the server's prompt catalog and keeps it current
+func (b *Bridge) watchPrompts(serverName string, client *MCPClient) {
+	caps := client.Capabilities()
+	if caps.Prompts == nil {
+		return
+	}
+
+	if caps.Prompts.ListChanged {
+		client.OnNotification("notifications/prompts/list_changed", func(json.RawMessage) {
+			if b.debug {
+				b.logger.Printf("Prompt list changed on server %s", serverName)
+			}
+			go b.refreshPrompts(serverName, client)
+		})
+	}
+
+	b.refreshPrompts(serverName, client)
+}
+
+// refreshPrompts replaces the cached prompt list of a server
+func (b *Bridge) refreshPrompts(serverName string, client *MCPClient) {
+	ctx, cancel := context.WithTimeout(b.ctx, b.serverConfig(serverName).RequestTimeout())
+	defer cancel()
+
+	prompts, err := client.ListPrompts(ctx)
+	if err != nil {
+		b.logger.Printf("Failed to list prompts for %s: %v", serverName, err)
+		return
+	}
+
+	b.mu.Lock()
+	b.prompts[serverName] = prompts
+	b.mu.Unlock()
+
+	if b.debug {
+		b.logger.Printf("Server %s offers %d prompts", serverName, len(prompts))
+	}
+}
+
+// ListPrompts returns the cached prompts of all servers, ordered by server and name
+func (b *Bridge) ListPrompts() []ServerPrompt {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var all []ServerPrompt
+	for server, prompts := range b.prompts {
+		for _, prompt := range prompts {
+			all = append(all, ServerPrompt{Server: server, Prompt: prompt})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Server != all[j].Server {
+			return all[i].Server < all[j].Server
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// GetPrompt expands a prompt into conversation messages. If server is empty,
+// the only server offering a prompt with that name is used.
+func (b *Bridge) GetPrompt(ctx context.Context, server, name string, args map[string]string) ([]types.Message, error) {
+	client, prompt, err := b.findPrompt(server, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, arg := range prompt.Arguments {
+		if _, ok := args[arg.Name]; arg.Required && !ok {
+			missing = append(missing, arg.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("prompt %s:%s is missing required arguments: %s", prompt.Server, name, strings.Join(missing, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.serverConfig(prompt.Server).RequestTimeout())
+	defer cancel()
+
+	result, err := client.GetPrompt(ctx, name, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt %s:%s: %w", prompt.Server, name, err)
+	}
+
+	messages := make([]types.Message, 0, len(result.Messages))
+	for _, msg := range result.Messages {
+		messages = append(messages, promptMessage(msg))
+	}
+	return messages, nil
+}
+
+// RunPrompt expands a prompt and processes the resulting conversation through
+// the LLM and tools
+func (b *Bridge) RunPrompt(ctx context.Context, server, name string, args map[string]string) (string, error) {
+	messages, err := b.GetPrompt(ctx, server, name, args)
+	if err != nil {
+		return "", err
+	}
+	if b.debug {
+		b.logger.Printf("Running prompt %s with %d messages", name, len(messages))
+	}
+	answer, err := b.ProcessMessages(ctx, messages)
+	if err != nil {
+		return "", err
+	}
+	return answer.Content, nil
+}
+
+// RunPromptInSession expands a prompt into a session's conversation, so the
+// model answers it with the earlier messages in view and later messages see
+// the prompt and its answer
+func (b *Bridge) RunPromptInSession(ctx context.Context, sessionID, server, name string, args map[string]string) (*Answer, error) {
+	messages, err := b.GetPrompt(ctx, server, name, args)
+	if err != nil {
+		return nil, err
+	}
+	if b.debug {
+		b.logger.Printf("Running prompt %s with %d messages in session %s", name, len(messages), sessionID)
+	}
+	return b.continueSession(ctx, sessionID, messages)
+}
+
+// findPrompt finds the client and catalog entry for a prompt, optionally
+// restricted to the named server
+func (b *Bridge) findPrompt(server, name string) (*MCPClient, ServerPrompt, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var matches []ServerPrompt
+	for serverName, prompts := range b.prompts {
+		if server != "" && serverName != server {
+			continue
+		}
+		for _, prompt := range prompts {
+			if prompt.Name == name {
+				matches = append(matches, ServerPrompt{Server: serverName, Prompt: prompt})
+				break
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		if server != "" {
+			return nil, ServerPrompt{}, fmt.Errorf("unknown prompt: %s:%s", server, name)
+		}
+		return nil, ServerPrompt{}, fmt.Errorf("unknown prompt: %s", name)
+	case 1:
+	default:
+		var servers []string
+		for _, match := range matches {
+			servers = append(servers, match.Server)
+		}
+		sort.Strings(servers)
+		return nil, ServerPrompt{}, fmt.Errorf("prompt %s is offered by several servers (%s); specify one", name, strings.Join(servers, ", "))
+	}
+
+	client, ok := b.serverMap[matches[0].Server]
+	if !ok {
+		return nil, ServerPrompt{}, fmt.Errorf("unknown MCP server: %s", matches[0].Server)
+	}
+	return client, matches[0], nil
+}
+
+// promptMessage converts a prompt message for the LLM. Image content is
+// passed to the model as an image, and anything else as text.
+func promptMessage(msg PromptMessage) types.Message {
+	message := types.Message{Role: msg.Role}
+	if msg.Content.Type == "image" {
+		message.Images = []string{msg.Content.Data}
+	} else {
+		message.Content = promptContentText(msg.Content)
+	}
+	return message
+}
+
+// promptContentText renders prompt message content as text for the LLM
+func promptContentText(content PromptContent) string {
+	switch content.Type {
+	case "text":
+		return content.Text
+	case "resource":
+		if content.Resource != nil {
+			return FormatResourceContents([]ResourceContents{*content.Resource})
+		}
+	}
+	return content.Text
+}
+
+// FormatPromptList renders prompts as one line each, with their arguments
+func FormatPromptList(prompts []ServerPrompt) string {
+	if len(prompts) == 0 {
+		return "No prompts available"
+	}
+
+	var sb strings.Builder
+	for _, p := range prompts {
+		sb.WriteString(fmt.Sprintf("/%s:%s", p.Server, p.Name))
+		for _, arg := range p.Arguments {
+			if arg.Required {
+				sb.WriteString(fmt.Sprintf(" %s=<value>", arg.Name))
+			} else {
+				sb.WriteString(fmt.Sprintf(" [%s=<value>]", arg.Name))
+			}
+		}
+		if p.Description != "" {
+			sb.WriteString(fmt.Sprintf(" - %s", p.Description))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,260 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sammcj/gomcp/config"
+)
+
+// watchTools refreshes a server's tools whenever it reports that they changed
+func (b *Bridge) watchTools(serverName string, client *MCPClient) {
+	caps := client.Capabilities()
+	if caps.Tools == nil || !caps.Tools.ListChanged {
+		return
+	}
+
+	client.OnNotification("notifications/tools/list_changed", func(json.RawMessage) {
+		if b.debug {
+			b.logger.Printf("Tool list changed on server %s", serverName)
+		}
+		go b.refreshTools(serverName, client)
+	})
+}
+
+// refreshTools fetches a server's current tools and rebuilds the registry
+func (b *Bridge) refreshTools(serverName string, client *MCPClient) {
+	ctx, cancel := context.WithTimeout(b.ctx, b.serverConfig(serverName).RequestTimeout())
+	defer cancel()
+
+	result, err := client.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		b.logger.Printf("Failed to refresh tools for %s: %v", serverName, err)
+		return
+	}
+
+	b.mu.Lock()
+	if b.serverTools == nil {
+		// The bridge closed while the tools were being fetched
+		b.mu.Unlock()
+		return
+	}
+	b.serverTools[serverName] = result.Tools
+	b.mu.Unlock()
+	b.cacheTools(serverName, result.Tools)
+
+	if err := b.rebuildTools(); err != nil {
+		b.logger.Printf("Failed to update tools after %s changed: %v", serverName, err)
+		return
+	}
+	b.logger.Printf("Server %s now offers %d tools", serverName, len(result.Tools))
+}
+
+// rebuildTools assembles the tool list and name mapping from the built-in
+// tools and every server's tools, swaps them in and hands them to the LLM
+func (b *Bridge) rebuildTools() error {
+	b.rebuildMu.Lock()
+	defer b.rebuildMu.Unlock()
+
+	builtins := append([]mcp.Tool(nil), b.builtins...)
+
+	// Let the LLM browse resources when any server offers them
+	if b.hasResources() {
+		builtins = append(builtins, resourceTools()...)
+	}
+
+	var offered []offeredTool
+	b.mu.RLock()
+	for _, serverCfg := range b.config.MCPServers {
+		for _, tool := range b.serverTools[serverCfg.Name] {
+			offered = append(offered, offeredTool{
+				server: serverCfg.Name,
+				tool:   tool,
+				alias:  serverCfg.ToolAliases[tool.Name],
+			})
+		}
+	}
+	b.mu.RUnlock()
+
+	registry, err := newToolRegistry(builtins, offered, b.config.ToolNamingStrategy())
+	if err != nil {
+		return err
+	}
+	for _, tool := range registry.tools {
+		ref := registry.names[tool.Name]
+		if ref.Server == "" {
+			continue
+		}
+		if tool.Name != validToolName(ref.Tool) {
+			b.logger.Printf("Tool %s from server %s is offered to the LLM as %s", ref.Tool, ref.Server, tool.Name)
+		} else if b.debug {
+			b.logger.Printf("Registered tool %s from server %s", ref.Tool, ref.Server)
+		}
+	}
+
+	// Set tools in LLM client
+	if b.debug {
+		b.logger.Printf("Setting %d tools in LLM client...", len(registry.tools))
+	}
+	if err := b.llmClient.SetTools(registry.tools); err != nil {
+		return fmt.Errorf("failed to set tools in LLM client: %w", err)
+	}
+
+	b.mu.Lock()
+	b.tools = registry.tools
+	b.toolMap = registry.names
+	b.mu.Unlock()
+	return nil
+}
+
+// lookupTool returns the tool an LLM tool name stands for
+func (b *Bridge) lookupTool(name string) (toolRef, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ref, ok := b.toolMap[name]
+	return ref, ok
+}
+
+// toolSchema returns the input schema of a tool by the name the LLM calls it
+func (b *Bridge) toolSchema(name string) (mcp.ToolInputSchema, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, tool := range b.tools {
+		if tool.Name == name {
+			return tool.InputSchema, true
+		}
+	}
+	return mcp.ToolInputSchema{}, false
+}
+
+// maxToolNameLength is the longest function name LLM APIs accept
+const maxToolNameLength = 64
+
+// toolRef identifies a tool by the server offering it and the server's own
+// name for it. Built-in tools have no server.
+type toolRef struct {
+	Server string
+	Tool   string
+}
+
+// offeredTool is a tool offered by a server, with the alias it was given in
+// the config if any
+type offeredTool struct {
+	server string
+	tool   mcp.Tool
+	alias  string
+}
+
+// wantedName returns the name the tool gets unless it clashes with another
+func (t offeredTool) wantedName() string {
+	if t.alias != "" {
+		return validToolName(t.alias)
+	}
+	return validToolName(t.tool.Name)
+}
+
+// toolRegistry holds the tools offered to the LLM under names unique across
+// the built-in tools and every server
+type toolRegistry struct {
+	tools []mcp.Tool         // Tools as the LLM sees them, under their registered names
+	names map[string]toolRef // Maps registered names to the tools they stand for
+}
+
+// newToolRegistry names the built-in tools and the tools offered by servers.
+// Built-in tools keep their names. A server tool keeps its name, or its
+// alias, unless another tool wants the same one; strategy then decides
+// whether the clashing tools are prefixed with their server's name or
+// rejected.
+func newToolRegistry(builtins []mcp.Tool, offered []offeredTool, strategy string) (*toolRegistry, error) {
+	names := make(map[string]toolRef)
+	for _, tool := range builtins {
+		names[tool.Name] = toolRef{Tool: tool.Name}
+	}
+
+	wanted := make(map[string][]string) // Maps wanted names to the servers wanting them
+	for _, t := range offered {
+		wanted[t.wantedName()] = append(wanted[t.wantedName()], t.server)
+	}
+
+	// Tools with a name to themselves keep it
+	assigned := make([]string, len(offered))
+	for n, t := range offered {
+		name := t.wantedName()
+		if _, builtin := names[name]; builtin || len(wanted[name]) > 1 {
+			continue
+		}
+		names[name] = toolRef{Server: t.server, Tool: t.tool.Name}
+		assigned[n] = name
+	}
+
+	// The rest clash with each other or with a built-in tool
+	for n, t := range offered {
+		if assigned[n] != "" {
+			continue
+		}
+		name := t.wantedName()
+		if strategy == config.ToolNamingError {
+			owners := wanted[name]
+			if ref, builtin := names[name]; builtin && ref.Server == "" {
+				owners = append([]string{"gomcp"}, owners...)
+			}
+			return nil, fmt.Errorf("tool name %s is offered by %s; rename with tool_aliases or set tool_naming to %s",
+				name, strings.Join(owners, ", "), config.ToolNamingPrefix)
+		}
+		name = uniqueToolName(validToolName(t.server+"__"+name), names)
+		names[name] = toolRef{Server: t.server, Tool: t.tool.Name}
+		assigned[n] = name
+	}
+
+	registry := &toolRegistry{tools: builtins, names: names}
+	for n, t := range offered {
+		tool := t.tool
+		tool.Name = assigned[n]
+		registry.tools = append(registry.tools, tool)
+	}
+	return registry, nil
+}
+
+// validToolName converts a name to one LLM APIs accept as a function name:
+// letters, digits and underscores, at most 64 characters
+func validToolName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	valid := sb.String()
+	if valid == "" {
+		valid = "tool"
+	}
+	if len(valid) > maxToolNameLength {
+		valid = valid[:maxToolNameLength]
+	}
+	return valid
+}
+
+// uniqueToolName returns name, or name with a numeric suffix if it is taken
+func uniqueToolName(name string, taken map[string]toolRef) string {
+	if _, ok := taken[name]; !ok {
+		return name
+	}
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf("_%d", n)
+		candidate := name
+		if len(candidate)+len(suffix) > maxToolNameLength {
+			candidate = candidate[:maxToolNameLength-len(suffix)]
+		}
+		candidate += suffix
+		if _, ok := taken[candidate]; !ok {
+			return candidate
+		}
+	}
+}
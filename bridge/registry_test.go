@@ -0,0 +1,187 @@
+package bridge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sammcj/gomcp/config"
+)
+
+func TestNewToolRegistry(t *testing.T) {
+	longName := strings.Repeat("x", 80)
+	longServer := strings.Repeat("s", 70)
+
+	tests := []struct {
+		name     string
+		builtins []string
+		offered  []offeredTool
+		strategy string
+		want     map[string]toolRef // registered name -> tool, built-ins included
+		wantErr  string
+	}{
+		{
+			name:     "no clashes",
+			builtins: []string{"query_database"},
+			offered: []offeredTool{
+				{server: "a", tool: mcp.Tool{Name: "query"}},
+				{server: "b", tool: mcp.Tool{Name: "list"}},
+			},
+			strategy: config.ToolNamingPrefix,
+			want: map[string]toolRef{
+				"query_database": {Tool: "query_database"},
+				"query":          {Server: "a", Tool: "query"},
+				"list":           {Server: "b", Tool: "list"},
+			},
+		},
+		{
+			name:     "clash with a built-in",
+			builtins: []string{"query_database"},
+			offered: []offeredTool{
+				{server: "sqlite", tool: mcp.Tool{Name: "query_database"}},
+			},
+			strategy: config.ToolNamingPrefix,
+			want: map[string]toolRef{
+				"query_database":         {Tool: "query_database"},
+				"sqlite__query_database": {Server: "sqlite", Tool: "query_database"},
+			},
+		},
+		{
+			name: "clash between servers",
+			offered: []offeredTool{
+				{server: "a", tool: mcp.Tool{Name: "query"}},
+				{server: "b", tool: mcp.Tool{Name: "query"}},
+				{server: "b", tool: mcp.Tool{Name: "list"}},
+			},
+			strategy: config.ToolNamingPrefix,
+			want: map[string]toolRef{
+				"a__query": {Server: "a", Tool: "query"},
+				"b__query": {Server: "b", Tool: "query"},
+				"list":     {Server: "b", Tool: "list"},
+			},
+		},
+		{
+			name: "alias avoids a clash",
+			offered: []offeredTool{
+				{server: "a", tool: mcp.Tool{Name: "query"}},
+				{server: "b", tool: mcp.Tool{Name: "query"}, alias: "query_b"},
+			},
+			strategy: config.ToolNamingError,
+			want: map[string]toolRef{
+				"query":   {Server: "a", Tool: "query"},
+				"query_b": {Server: "b", Tool: "query"},
+			},
+		},
+		{
+			name:     "alias clashing with a built-in",
+			builtins: []string{"read_resource"},
+			offered: []offeredTool{
+				{server: "files", tool: mcp.Tool{Name: "read"}, alias: "read_resource"},
+			},
+			strategy: config.ToolNamingPrefix,
+			want: map[string]toolRef{
+				"read_resource":        {Tool: "read_resource"},
+				"files__read_resource": {Server: "files", Tool: "read"},
+			},
+		},
+		{
+			name: "invalid characters",
+			offered: []offeredTool{
+				{server: "my.server", tool: mcp.Tool{Name: "get-price"}},
+				{server: "other", tool: mcp.Tool{Name: "get price"}},
+			},
+			strategy: config.ToolNamingPrefix,
+			want: map[string]toolRef{
+				"my_server__get_price": {Server: "my.server", Tool: "get-price"},
+				"other__get_price":     {Server: "other", Tool: "get price"},
+			},
+		},
+		{
+			name: "long name truncated to 64 characters",
+			offered: []offeredTool{
+				{server: "a", tool: mcp.Tool{Name: longName}},
+			},
+			strategy: config.ToolNamingPrefix,
+			want: map[string]toolRef{
+				strings.Repeat("x", 64): {Server: "a", Tool: longName},
+			},
+		},
+		{
+			name: "prefixed name already taken gets a suffix",
+			offered: []offeredTool{
+				{server: "c", tool: mcp.Tool{Name: "a__query"}},
+				{server: "a", tool: mcp.Tool{Name: "query"}},
+				{server: "b", tool: mcp.Tool{Name: "query"}},
+			},
+			strategy: config.ToolNamingPrefix,
+			want: map[string]toolRef{
+				"a__query":   {Server: "c", Tool: "a__query"},
+				"a__query_2": {Server: "a", Tool: "query"},
+				"b__query":   {Server: "b", Tool: "query"},
+			},
+		},
+		{
+			name: "suffix kept within 64 characters",
+			offered: []offeredTool{
+				{server: longServer + "1", tool: mcp.Tool{Name: "query"}},
+				{server: longServer + "2", tool: mcp.Tool{Name: "query"}},
+			},
+			strategy: config.ToolNamingPrefix,
+			want: map[string]toolRef{
+				strings.Repeat("s", 64):        {Server: longServer + "1", Tool: "query"},
+				strings.Repeat("s", 62) + "_2": {Server: longServer + "2", Tool: "query"},
+			},
+		},
+		{
+			name: "error mode rejects a clash between servers",
+			offered: []offeredTool{
+				{server: "a", tool: mcp.Tool{Name: "query"}},
+				{server: "b", tool: mcp.Tool{Name: "query"}},
+			},
+			strategy: config.ToolNamingError,
+			wantErr:  "tool name query is offered by a, b",
+		},
+		{
+			name:     "error mode rejects a clash with a built-in",
+			builtins: []string{"query_database"},
+			offered: []offeredTool{
+				{server: "sqlite", tool: mcp.Tool{Name: "query_database"}},
+			},
+			strategy: config.ToolNamingError,
+			wantErr:  "tool name query_database is offered by gomcp, sqlite",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var builtins []mcp.Tool
+			for _, name := range tt.builtins {
+				builtins = append(builtins, mcp.Tool{Name: name})
+			}
+
+			registry, err := newToolRegistry(builtins, tt.offered, tt.strategy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newToolRegistry: %v", err)
+			}
+
+			if !reflect.DeepEqual(registry.names, tt.want) {
+				t.Errorf("names = %v, want %v", registry.names, tt.want)
+			}
+			if len(registry.tools) != len(tt.want) {
+				t.Fatalf("registered %d tools, want %d", len(registry.tools), len(tt.want))
+			}
+			for _, tool := range registry.tools {
+				if _, ok := tt.want[tool.Name]; !ok {
+					t.Errorf("tool offered to the LLM as %q, which isn't registered", tool.Name)
+				}
+			}
+		})
+	}
+}
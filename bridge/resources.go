@@ -0,0 +1,372 @@
+package bridge
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sammcj/gomcp/types"
+)
+
+// Resource describes a piece of data an MCP server makes available
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceTemplate describes a family of resources addressed by a URI template
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents holds the contents of a resource. Text resources set Text;
+// binary resources set Blob to base64-encoded data.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MIMEType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// ServerResource is a resource together with the server offering it
+type ServerResource struct {
+	Server string
+	Resource
+}
+
+// Built-in tools that give the LLM access to server resources
+const (
+	listResourcesTool = "list_resources"
+	readResourceTool  = "read_resource"
+)
+
+// ListResources returns every resource the server offers
+func (c *MCPClient) ListResources(ctx context.Context) ([]Resource, error) {
+	c.logger.Printf("Sending list resources request")
+	return listAll[Resource](ctx, c, "resources/list", "resources")
+}
+
+// ListResourceTemplates returns every resource template the server offers
+func (c *MCPClient) ListResourceTemplates(ctx context.Context) ([]ResourceTemplate, error) {
+	c.logger.Printf("Sending list resource templates request")
+	return listAll[ResourceTemplate](ctx, c, "resources/templates/list", "resourceTemplates")
+}
+
+// ReadResource fetches the contents of the resource at uri
+func (c *MCPClient) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	c.logger.Printf("Sending read resource request: %s", uri)
+
+	respBytes, err := c.call(ctx, "resources/read", map[string]interface{}{"uri": uri})
+	if err != nil {
+		c.logger.Printf("Read resource request failed: %v", err)
+		return nil, err
+	}
+
+	var resp struct {
+		Result struct {
+			Contents []ResourceContents `json:"contents"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		c.logger.Printf("Failed to unmarshal response: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return resp.Result.Contents, nil
+}
+
+// SubscribeResource asks the server to send notifications/resources/updated when uri changes
+func (c *MCPClient) SubscribeResource(ctx context.Context, uri string) error {
+	c.logger.Printf("Sending resource subscribe request: %s", uri)
+	_, err := c.call(ctx, "resources/subscribe", map[string]interface{}{"uri": uri})
+	return err
+}
+
+// UnsubscribeResource cancels a subscription made with SubscribeResource
+func (c *MCPClient) UnsubscribeResource(ctx context.Context, uri string) error {
+	c.logger.Printf("Sending resource unsubscribe request: %s", uri)
+	_, err := c.call(ctx, "resources/unsubscribe", map[string]interface{}{"uri": uri})
+	return err
+}
+
+// watchResources loads the server's resource catalog and keeps it current
+func (b *Bridge) watchResources(serverName string, client *MCPClient) {
+	caps := client.Capabilities()
+	if caps.Resources == nil {
+		return
+	}
+
+	if caps.Resources.ListChanged {
+		client.OnNotification("notifications/resources/list_changed", func(json.RawMessage) {
+			if b.debug {
+				b.logger.Printf("Resource list changed on server %s", serverName)
+			}
+			go b.refreshResources(serverName, client)
+		})
+	}
+	client.OnNotification("notifications/resources/updated", func(params json.RawMessage) {
+		var updated struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(params, &updated); err != nil {
+			b.logger.Printf("Invalid resource update from server %s: %v", serverName, err)
+			return
+		}
+		if b.debug {
+			b.logger.Printf("Resource %s updated on server %s", updated.URI, serverName)
+		}
+
+		b.mu.RLock()
+		handler := b.resourceUpdated
+		b.mu.RUnlock()
+		if handler != nil {
+			handler(serverName, updated.URI)
+		}
+	})
+
+	b.refreshResources(serverName, client)
+}
+
+// refreshResources replaces the cached resource list of a server
+func (b *Bridge) refreshResources(serverName string, client *MCPClient) {
+	ctx, cancel := context.WithTimeout(b.ctx, b.serverConfig(serverName).RequestTimeout())
+	defer cancel()
+
+	resources, err := client.ListResources(ctx)
+	if err != nil {
+		b.logger.Printf("Failed to list resources for %s: %v", serverName, err)
+		return
+	}
+
+	b.mu.Lock()
+	b.resources[serverName] = resources
+	b.mu.Unlock()
+
+	if b.debug {
+		b.logger.Printf("Server %s offers %d resources", serverName, len(resources))
+	}
+}
+
+// SetResourceUpdateHandler registers a function called when a subscribed resource changes
+func (b *Bridge) SetResourceUpdateHandler(handler func(server, uri string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resourceUpdated = handler
+}
+
+// ListResources returns the cached resources of all servers, ordered by server and URI
+func (b *Bridge) ListResources() []ServerResource {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var all []ServerResource
+	for server, resources := range b.resources {
+		for _, resource := range resources {
+			all = append(all, ServerResource{Server: server, Resource: resource})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Server != all[j].Server {
+			return all[i].Server < all[j].Server
+		}
+		return all[i].URI < all[j].URI
+	})
+	return all
+}
+
+// ReadResource reads a resource. If server is empty, the server whose catalog
+// lists uri is used.
+func (b *Bridge) ReadResource(ctx context.Context, server, uri string) ([]ResourceContents, error) {
+	client, server, err := b.resourceClient(server, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.serverConfig(server).RequestTimeout())
+	defer cancel()
+	return client.ReadResource(ctx, uri)
+}
+
+// SubscribeResource subscribes to updates of a resource, if its server supports it
+func (b *Bridge) SubscribeResource(ctx context.Context, server, uri string) error {
+	client, server, err := b.resourceClient(server, uri)
+	if err != nil {
+		return err
+	}
+	if caps := client.Capabilities(); caps.Resources == nil || !caps.Resources.Subscribe {
+		return fmt.Errorf("server %s does not support resource subscriptions", server)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.serverConfig(server).RequestTimeout())
+	defer cancel()
+	return client.SubscribeResource(ctx, uri)
+}
+
+// UnsubscribeResource cancels a subscription made with SubscribeResource
+func (b *Bridge) UnsubscribeResource(ctx context.Context, server, uri string) error {
+	client, server, err := b.resourceClient(server, uri)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.serverConfig(server).RequestTimeout())
+	defer cancel()
+	return client.UnsubscribeResource(ctx, uri)
+}
+
+// resourceClient finds the client serving uri, optionally restricted to the named server
+func (b *Bridge) resourceClient(server, uri string) (*MCPClient, string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if server == "" {
+		var matches []string
+		for name, resources := range b.resources {
+			for _, resource := range resources {
+				if resource.URI == uri {
+					matches = append(matches, name)
+					break
+				}
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, "", fmt.Errorf("unknown resource: %s", uri)
+		case 1:
+			server = matches[0]
+		default:
+			sort.Strings(matches)
+			return nil, "", fmt.Errorf("resource %s is offered by several servers (%s); specify one", uri, strings.Join(matches, ", "))
+		}
+	}
+
+	client, ok := b.serverMap[server]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown MCP server: %s", server)
+	}
+	return client, server, nil
+}
+
+// hasResources reports whether any connected server offers resources
+func (b *Bridge) hasResources() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, client := range b.serverMap {
+		if client.Capabilities().Resources != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceTools returns the built-in tool definitions for browsing resources
+func resourceTools() []mcp.Tool {
+	return []mcp.Tool{
+		{
+			Name:        listResourcesTool,
+			Description: "List the resources (files, records, documents) available from connected MCP servers",
+			InputSchema: mcp.ToolInputSchema{
+				Type:       "object",
+				Properties: map[string]interface{}{},
+			},
+		},
+		{
+			Name:        readResourceTool,
+			Description: "Read the contents of a resource by its URI, as returned by list_resources",
+			InputSchema: mcp.ToolInputSchema{
+				Type: "object",
+				Properties: map[string]interface{}{
+					"uri": map[string]interface{}{
+						"type":        "string",
+						"description": "URI of the resource to read",
+					},
+					"server": map[string]interface{}{
+						"type":        "string",
+						"description": "Name of the server offering the resource, if more than one does",
+					},
+				},
+				Required: []string{"uri"},
+			},
+		},
+	}
+}
+
+// handleResourceTool processes calls to the built-in resource tools
+func (b *Bridge) handleResourceTool(ctx context.Context, call types.ToolCall) (map[string]interface{}, error) {
+	var output string
+	switch call.Function.Name {
+	case listResourcesTool:
+		output = FormatResourceList(b.ListResources())
+
+	case readResourceTool:
+		uri, ok := call.Function.Arguments["uri"].(string)
+		if !ok || uri == "" {
+			return nil, fmt.Errorf("invalid uri argument")
+		}
+		server, _ := call.Function.Arguments["server"].(string)
+
+		contents, err := b.ReadResource(ctx, server, uri)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource: %w", err)
+		}
+		output = FormatResourceContents(contents)
+	}
+
+	return map[string]interface{}{
+		"tool_call_id": call.ID,
+		"output":       output,
+	}, nil
+}
+
+// FormatResourceList renders resources as one line each
+func FormatResourceList(resources []ServerResource) string {
+	if len(resources) == 0 {
+		return "No resources available"
+	}
+
+	var sb strings.Builder
+	for _, r := range resources {
+		sb.WriteString(fmt.Sprintf("[%s] %s", r.Server, r.URI))
+		if r.Name != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", r.Name))
+		}
+		if r.MIMEType != "" {
+			sb.WriteString(fmt.Sprintf(" %s", r.MIMEType))
+		}
+		if r.Description != "" {
+			sb.WriteString(fmt.Sprintf(" - %s", r.Description))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// FormatResourceContents renders resource contents as text, summarising binary data
+func FormatResourceContents(contents []ResourceContents) string {
+	var sb strings.Builder
+	for _, c := range contents {
+		sb.WriteString(fmt.Sprintf("--- %s", c.URI))
+		if c.MIMEType != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", c.MIMEType))
+		}
+		sb.WriteString(" ---\n")
+
+		if c.Blob != "" {
+			size := base64.StdEncoding.DecodedLen(len(c.Blob))
+			sb.WriteString(fmt.Sprintf("[binary content, about %d bytes]\n", size))
+			continue
+		}
+		sb.WriteString(c.Text)
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,186 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sammcj/gomcp/config"
+)
+
+// Root is a directory the user has granted MCP servers access to
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// NotifyRootsChanged tells the server that the list of roots has changed
+func (c *MCPClient) NotifyRootsChanged(ctx context.Context) error {
+	return c.sendNotification(ctx, "notifications/roots/list_changed", nil)
+}
+
+// enableRoots advertises the roots capability and answers roots/list for a server
+func (b *Bridge) enableRoots(serverCfg config.MCPServerConfig, client *MCPClient) {
+	client.SetCapability("roots", map[string]interface{}{"listChanged": true})
+	client.OnRequest("roots/list", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		roots := b.Roots(serverCfg.Name)
+		if b.debug {
+			b.logger.Printf("Sharing %d roots with %s", len(roots), serverCfg.Name)
+		}
+		if roots == nil {
+			roots = []Root{}
+		}
+		return map[string]interface{}{"roots": roots}, nil
+	})
+}
+
+// Roots returns the roots shared with the named server: the global roots
+// followed by the server's own
+func (b *Bridge) Roots(server string) []Root {
+	b.mu.RLock()
+	uris := append([]string(nil), b.roots...)
+	b.mu.RUnlock()
+
+	for _, root := range b.serverConfig(server).Roots {
+		uri, err := rootURI(root)
+		if err != nil {
+			b.logger.Printf("Ignoring invalid root %q for %s: %v", root, server, err)
+			continue
+		}
+		uris = append(uris, uri)
+	}
+
+	var roots []Root
+	seen := make(map[string]bool)
+	for _, uri := range uris {
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		roots = append(roots, Root{URI: uri, Name: rootName(uri)})
+	}
+	return roots
+}
+
+// AddRoot grants every server access to a directory and tells them the roots changed
+func (b *Bridge) AddRoot(ctx context.Context, root string) error {
+	uri, err := rootURI(root)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	for _, existing := range b.roots {
+		if existing == uri {
+			b.mu.Unlock()
+			return fmt.Errorf("root %s is already shared", uri)
+		}
+	}
+	b.roots = append(b.roots, uri)
+	b.mu.Unlock()
+
+	b.notifyRootsChanged(ctx)
+	return nil
+}
+
+// RemoveRoot revokes a root added globally and tells the servers the roots changed
+func (b *Bridge) RemoveRoot(ctx context.Context, root string) error {
+	uri, err := rootURI(root)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	removed := false
+	for i, existing := range b.roots {
+		if existing == uri {
+			b.roots = append(b.roots[:i], b.roots[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if !removed {
+		return fmt.Errorf("root %s is not shared", uri)
+	}
+	b.notifyRootsChanged(ctx)
+	return nil
+}
+
+// notifyRootsChanged sends notifications/roots/list_changed to every server
+func (b *Bridge) notifyRootsChanged(ctx context.Context) {
+	b.mu.RLock()
+	clients := make(map[string]*MCPClient, len(b.serverMap))
+	for name, client := range b.serverMap {
+		clients[name] = client
+	}
+	b.mu.RUnlock()
+
+	for name, client := range clients {
+		if err := client.NotifyRootsChanged(ctx); err != nil {
+			b.logger.Printf("Failed to notify %s of changed roots: %v", name, err)
+		}
+	}
+}
+
+// rootURI converts a path, which may start with ~, or a file:// URI to an
+// absolute file:// URI
+func rootURI(root string) (string, error) {
+	if root == "" {
+		return "", fmt.Errorf("root must not be empty")
+	}
+
+	if strings.Contains(root, "://") {
+		u, err := url.Parse(root)
+		if err != nil {
+			return "", fmt.Errorf("invalid root %q: %w", root, err)
+		}
+		if u.Scheme != "file" {
+			return "", fmt.Errorf("root %q must be a file:// URI", root)
+		}
+		return u.String(), nil
+	}
+
+	if root == "~" || strings.HasPrefix(root, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %q: %w", root, err)
+		}
+		root = filepath.Join(home, root[1:])
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("invalid root %q: %w", root, err)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// rootName returns the last path element of a root URI, for display
+func rootName(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Path == "" {
+		return ""
+	}
+	return path.Base(u.Path)
+}
+
+// FormatRoots renders roots as one URI per line
+func FormatRoots(roots []Root) string {
+	if len(roots) == 0 {
+		return "No roots shared"
+	}
+
+	var sb strings.Builder
+	for _, root := range roots {
+		sb.WriteString(root.URI)
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
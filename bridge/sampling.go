@@ -0,0 +1,228 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sammcj/gomcp/config"
+	"github.com/sammcj/gomcp/llm"
+	"github.com/sammcj/gomcp/types"
+)
+
+// SamplingRequest is a completion an MCP server asked the bridge to run
+type SamplingRequest struct {
+	Server       string
+	Messages     []types.Message
+	SystemPrompt string
+	MaxTokens    int
+	Model        string
+}
+
+// SamplingApprover decides whether a sampling request may run. It is asked
+// for servers whose sampling policy is ask.
+type SamplingApprover func(ctx context.Context, req SamplingRequest) bool
+
+// TokenUsage counts the LLM tokens spent on behalf of a server
+type TokenUsage struct {
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// samplingParams is the params object of a sampling/createMessage request
+type samplingParams struct {
+	Messages []struct {
+		Role    string        `json:"role"`
+		Content PromptContent `json:"content"`
+	} `json:"messages"`
+	ModelPreferences *struct {
+		Hints []struct {
+			Name string `json:"name"`
+		} `json:"hints"`
+	} `json:"modelPreferences"`
+	SystemPrompt  string   `json:"systemPrompt"`
+	Temperature   *float64 `json:"temperature"`
+	MaxTokens     int      `json:"maxTokens"`
+	StopSequences []string `json:"stopSequences"`
+}
+
+// enableSampling lets a server request completions unless its policy denies it
+func (b *Bridge) enableSampling(serverCfg config.MCPServerConfig, client *MCPClient) {
+	if serverCfg.SamplingPolicy() == config.SamplingDeny {
+		return
+	}
+
+	client.SetCapability("sampling", map[string]interface{}{})
+	client.OnRequest("sampling/createMessage", func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+		return b.handleSampling(ctx, serverCfg, params)
+	})
+}
+
+// SetSamplingApprover registers the function asked to approve sampling requests
+func (b *Bridge) SetSamplingApprover(approver SamplingApprover) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.samplingApprover = approver
+}
+
+// SamplingUsage returns the tokens spent on sampling requests, keyed by server name
+func (b *Bridge) SamplingUsage() map[string]TokenUsage {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	usage := make(map[string]TokenUsage, len(b.usage))
+	for server, u := range b.usage {
+		usage[server] = *u
+	}
+	return usage
+}
+
+// handleSampling runs a sampling/createMessage request through the LLM
+func (b *Bridge) handleSampling(ctx context.Context, serverCfg config.MCPServerConfig, raw json.RawMessage) (interface{}, error) {
+	var params samplingParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &types.RPCError{Code: -32602, Message: fmt.Sprintf("invalid sampling params: %v", err)}
+	}
+	if len(params.Messages) == 0 {
+		return nil, &types.RPCError{Code: -32602, Message: "sampling request has no messages"}
+	}
+
+	req := SamplingRequest{
+		Server:       serverCfg.Name,
+		SystemPrompt: params.SystemPrompt,
+		MaxTokens:    params.MaxTokens,
+	}
+	for _, msg := range params.Messages {
+		req.Messages = append(req.Messages, promptMessage(PromptMessage(msg)))
+	}
+	if params.ModelPreferences != nil {
+		var hints []string
+		for _, hint := range params.ModelPreferences.Hints {
+			hints = append(hints, hint.Name)
+		}
+		req.Model = selectSamplingModel(serverCfg.Sampling.Models, hints)
+	}
+
+	if !b.approveSampling(ctx, serverCfg, req) {
+		b.logger.Printf("Sampling request from %s was rejected", serverCfg.Name)
+		return nil, &types.RPCError{Code: -1, Message: "User rejected sampling request"}
+	}
+
+	if b.debug {
+		b.logger.Printf("Running sampling request from %s with %d messages", serverCfg.Name, len(req.Messages))
+	}
+	completion, err := b.llmClient.Complete(ctx, llm.CompletionRequest{
+		Model:         req.Model,
+		SystemPrompt:  req.SystemPrompt,
+		Messages:      req.Messages,
+		MaxTokens:     req.MaxTokens,
+		Temperature:   params.Temperature,
+		StopSequences: params.StopSequences,
+	})
+	if err != nil {
+		b.logger.Printf("Sampling request from %s failed: %v", serverCfg.Name, err)
+		return nil, fmt.Errorf("completion failed: %w", err)
+	}
+
+	b.recordUsage(serverCfg.Name, completion)
+
+	stopReason := "endTurn"
+	switch completion.StopReason {
+	case "length":
+		stopReason = "maxTokens"
+	case "stop", "":
+	default:
+		stopReason = completion.StopReason
+	}
+
+	return map[string]interface{}{
+		"role": "assistant",
+		"content": map[string]interface{}{
+			"type": "text",
+			"text": completion.Content,
+		},
+		"model":      completion.Model,
+		"stopReason": stopReason,
+	}, nil
+}
+
+// approveSampling applies the server's sampling policy to a request
+func (b *Bridge) approveSampling(ctx context.Context, serverCfg config.MCPServerConfig, req SamplingRequest) bool {
+	switch serverCfg.SamplingPolicy() {
+	case config.SamplingAllow:
+		return true
+	case config.SamplingAsk:
+		b.mu.RLock()
+		approver := b.samplingApprover
+		b.mu.RUnlock()
+
+		// Without a user to ask, there is nobody to approve the request
+		if approver == nil {
+			return false
+		}
+		return approver(ctx, req)
+	default:
+		return false
+	}
+}
+
+// recordUsage adds the tokens of a completion to the server's running total
+func (b *Bridge) recordUsage(server string, completion *llm.Completion) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	usage, ok := b.usage[server]
+	if !ok {
+		usage = &TokenUsage{}
+		b.usage[server] = usage
+	}
+	usage.Requests++
+	usage.PromptTokens += completion.PromptTokens
+	usage.CompletionTokens += completion.CompletionTokens
+
+	if b.debug {
+		b.logger.Printf("Sampling for %s used %d prompt and %d completion tokens (%d and %d in total)", server,
+			completion.PromptTokens, completion.CompletionTokens, usage.PromptTokens, usage.CompletionTokens)
+	}
+}
+
+// selectSamplingModel returns the first allowed model matching one of the
+// server's hints, in the server's order of preference. Hints are substrings
+// of model names. An empty result means the configured model.
+func selectSamplingModel(allowed []string, hints []string) string {
+	for _, hint := range hints {
+		if hint == "" {
+			continue
+		}
+		for _, model := range allowed {
+			if strings.Contains(strings.ToLower(model), strings.ToLower(hint)) {
+				return model
+			}
+		}
+	}
+	return ""
+}
+
+// FormatSamplingUsage renders per-server token usage as one line each
+func FormatSamplingUsage(usage map[string]TokenUsage) string {
+	if len(usage) == 0 {
+		return "No sampling requests yet"
+	}
+
+	servers := make([]string, 0, len(usage))
+	for server := range usage {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+
+	var sb strings.Builder
+	for _, server := range servers {
+		u := usage[server]
+		sb.WriteString(fmt.Sprintf("%s: %d requests, %d prompt tokens, %d completion tokens\n",
+			server, u.Requests, u.PromptTokens, u.CompletionTokens))
+	}
+	return strings.TrimSpace(sb.String())
+}
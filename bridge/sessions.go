@@ -0,0 +1,212 @@
+package bridge
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sammcj/gomcp/types"
+)
+
+// sessionStore keeps conversations in SQLite so they survive restarts
+type sessionStore struct {
+	db *sql.DB
+}
+
+// openSessionStore opens the session database at path, creating it if need be
+func openSessionStore(path string) (*sessionStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+	// SQLite allows one writer at a time, so share a single connection
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS sessions (
+			id         TEXT PRIMARY KEY,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS session_messages (
+			session_id TEXT NOT NULL REFERENCES sessions(id),
+			seq        INTEGER NOT NULL,
+			role       TEXT NOT NULL,
+			message    TEXT NOT NULL,
+			PRIMARY KEY (session_id, seq)
+		);
+	`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create session tables: %w", err)
+	}
+	return &sessionStore{db: db}, nil
+}
+
+// Create starts an empty session and returns its ID
+func (s *sessionStore) Create() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	id := hex.EncodeToString(buf)
+
+	now := time.Now().UTC()
+	if _, err := s.db.Exec(`INSERT INTO sessions (id, created_at, updated_at) VALUES (?, ?, ?)`, id, now, now); err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	return id, nil
+}
+
+// Messages returns the conversation held by a session, oldest first
+func (s *sessionStore) Messages(id string) ([]types.Message, error) {
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM sessions WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("unknown session: %s", id)
+	}
+
+	rows, err := s.db.Query(`SELECT message FROM session_messages WHERE session_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []types.Message
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to load session: %w", err)
+		}
+		var msg types.Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, fmt.Errorf("invalid message in session %s: %w", id, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// Append adds messages to the end of a session's conversation
+func (s *sessionStore) Append(id string, messages []types.Message) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	defer tx.Rollback()
+
+	var next int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(seq), 0) + 1 FROM session_messages WHERE session_id = ?`, id).Scan(&next); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	for n, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to encode message: %w", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO session_messages (session_id, seq, role, message) VALUES (?, ?, ?, ?)`,
+			id, next+n, msg.Role, string(data)); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+	if _, err := tx.Exec(`UPDATE sessions SET updated_at = ? WHERE id = ?`, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Close closes the session database
+func (s *sessionStore) Close() error {
+	return s.db.Close()
+}
+
+// NewSession starts a conversation whose history is kept between messages
+// and restarts
+func (b *Bridge) NewSession() (string, error) {
+	return b.sessions.Create()
+}
+
+// SessionMessages returns the conversation held by a session
+func (b *Bridge) SessionMessages(id string) ([]types.Message, error) {
+	return b.sessions.Messages(id)
+}
+
+// Chat sends a message in a session, so the model sees the conversation so
+// far including earlier tool calls and their results. The exchange is only
+// saved if the model answers.
+func (b *Bridge) Chat(ctx context.Context, sessionID, msg string) (*Answer, error) {
+	if b.debug {
+		b.logger.Printf("Processing message in session %s: %s", sessionID, msg)
+	}
+	return b.continueSession(ctx, sessionID, []types.Message{{Role: "user", Content: msg}})
+}
+
+// continueSession adds messages to the end of a session's conversation and
+// has the model answer them, saving the messages and the answer together
+func (b *Bridge) continueSession(ctx context.Context, sessionID string, messages []types.Message) (*Answer, error) {
+	// Turns in one session must not interleave
+	unlock := b.lockSession(sessionID)
+	defer unlock()
+
+	history, err := b.sessions.Messages(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if b.debug {
+		b.logger.Printf("Continuing session %s after %d earlier messages", sessionID, len(history))
+	}
+
+	answer, err := b.ProcessMessages(ctx, append(history, messages...))
+	if err != nil {
+		return answer, err
+	}
+
+	if err := b.sessions.Append(sessionID, append(append([]types.Message(nil), messages...), answer.Messages...)); err != nil {
+		return answer, &types.BridgeError{
+			Operation: "save_session",
+			Message:   "failed to save session",
+			Err:       err,
+		}
+	}
+	return answer, nil
+}
+
+// sessionLock serialises the turns of a session. It is shared by the turns
+// running or waiting to run, and dropped once the last of them ends.
+type sessionLock struct {
+	sync.Mutex
+	refs int // turns holding or waiting for the lock, guarded by Bridge.sessionMu
+}
+
+// lockSession waits for any other turn in a session to end, and returns the
+// function that ends this one
+func (b *Bridge) lockSession(id string) func() {
+	b.sessionMu.Lock()
+	lock, ok := b.sessionLocks[id]
+	if !ok {
+		lock = &sessionLock{}
+		b.sessionLocks[id] = lock
+	}
+	lock.refs++
+	b.sessionMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		b.sessionMu.Lock()
+		defer b.sessionMu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(b.sessionLocks, id)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sammcj/gomcp/config"
+)
+
+// maxReconnectBackoff caps the delay between attempts to re-open a dropped stream
+const maxReconnectBackoff = 30 * time.Second
+
+// sseTransport connects to a remote MCP server using the HTTP+SSE transport.
+// Server messages arrive on a long-lived GET event stream, which first
+// announces the endpoint that client messages must be POSTed to.
+type sseTransport struct {
+	url      string
+	headers  map[string]string
+	logger   *log.Logger
+	client   *http.Client
+	messages chan []byte
+
+	mu           sync.Mutex
+	endpoint     string
+	ready        chan struct{} // closed once the current stream has announced its endpoint
+	reconnecting bool
+	onReconnect  func()
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// sseEvent is a single event read from a text/event-stream
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// newSSETransport creates a transport for the server URL in cfg
+func newSSETransport(cfg config.MCPServerConfig, logger *log.Logger) *sseTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &sseTransport{
+		url:      cfg.URL,
+		headers:  cfg.Headers,
+		logger:   logger,
+		client:   &http.Client{},
+		messages: make(chan []byte, 16),
+		ready:    make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start opens the event stream and waits for the server to announce its endpoint
+func (t *sseTransport) Start(ctx context.Context) error {
+	t.logger.Printf("Connecting to MCP server at %s", t.url)
+
+	// Abandoning the start tears the transport down; a successful start doesn't
+	stop := context.AfterFunc(ctx, t.cancel)
+	defer stop()
+
+	body, err := t.connect()
+	if err != nil {
+		t.cancel()
+		return err
+	}
+	go t.run(body)
+
+	select {
+	case <-t.readyChan():
+		return nil
+	case <-t.ctx.Done():
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("waiting for SSE endpoint from %s: %w", t.url, err)
+		}
+		return fmt.Errorf("transport closed while waiting for SSE endpoint from %s", t.url)
+	}
+}
+
+// connect issues the GET request for the event stream
+func (t *sseTransport) connect() (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", t.url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return resp.Body, nil
+}
+
+// run reads the event stream, reconnecting with backoff whenever it drops,
+// until the transport is closed
+func (t *sseTransport) run(body io.ReadCloser) {
+	defer close(t.messages)
+
+	for {
+		err := readSSE(body, t.handleEvent)
+		body.Close()
+		if t.ctx.Err() != nil {
+			return
+		}
+		t.logger.Printf("SSE stream from %s dropped: %v", t.url, err)
+		t.resetEndpoint()
+
+		backoff := time.Second
+		for {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			body, err = t.connect()
+			if err == nil {
+				t.logger.Printf("Reconnected to %s", t.url)
+				break
+			}
+			t.logger.Printf("Reconnecting to %s failed: %v", t.url, err)
+			backoff = min(backoff*2, maxReconnectBackoff)
+		}
+	}
+}
+
+// handleEvent processes a single event from the stream
+func (t *sseTransport) handleEvent(event sseEvent) error {
+	switch event.Event {
+	case "endpoint":
+		endpoint, err := t.resolve(event.Data)
+		if err != nil {
+			return fmt.Errorf("invalid endpoint %q: %w", event.Data, err)
+		}
+
+		t.mu.Lock()
+		if t.endpoint == "" {
+			close(t.ready)
+		}
+		t.endpoint = endpoint
+		reconnected := t.reconnecting
+		t.reconnecting = false
+		handler := t.onReconnect
+		t.mu.Unlock()
+
+		// The handler sends requests whose responses arrive on this stream, so it can't block it
+		if reconnected && handler != nil {
+			go handler()
+		}
+
+	case "message":
+		if !json.Valid([]byte(event.Data)) {
+			t.logger.Printf("Skipping invalid JSON message from %s", t.url)
+			return nil
+		}
+		select {
+		case t.messages <- []byte(event.Data):
+		case <-t.ctx.Done():
+			return t.ctx.Err()
+		}
+	}
+	return nil
+}
+
+// resolve turns the announced endpoint into an absolute URL
+func (t *sseTransport) resolve(endpoint string) (string, error) {
+	base, err := url.Parse(t.url)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// resetEndpoint forgets the endpoint of a dropped stream so sends wait for the next one
+func (t *sseTransport) resetEndpoint() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.endpoint != "" {
+		t.endpoint = ""
+		t.ready = make(chan struct{})
+	}
+	t.reconnecting = true
+}
+
+// readyChan returns the channel closed once the current stream has an endpoint
+func (t *sseTransport) readyChan() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ready
+}
+
+// currentEndpoint returns the endpoint for POSTs, waiting for one if the stream is reconnecting
+func (t *sseTransport) currentEndpoint(ctx context.Context) (string, error) {
+	for {
+		t.mu.Lock()
+		endpoint, ready := t.endpoint, t.ready
+		t.mu.Unlock()
+
+		if endpoint != "" {
+			return endpoint, nil
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-t.ctx.Done():
+			return "", fmt.Errorf("transport closed")
+		}
+	}
+}
+
+// Messages returns the channel of messages received on the event stream
+func (t *sseTransport) Messages() <-chan []byte {
+	return t.messages
+}
+
+// Send POSTs a message to the endpoint announced by the server
+func (t *sseTransport) Send(ctx context.Context, msg []byte) error {
+	endpoint, err := t.currentEndpoint(ctx)
+	if err != nil {
+		return fmt.Errorf("no endpoint available: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// SetReconnectHandler registers a function to run after the stream reconnects
+func (t *sseTransport) SetReconnectHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onReconnect = handler
+}
+
+// Close closes the event stream and stops reconnecting
+func (t *sseTransport) Close() error {
+	t.cancel()
+	return nil
+}
+
+// setHeaders applies the configured headers, e.g. for authentication
+func (t *sseTransport) setHeaders(req *http.Request) {
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// readSSE parses a text/event-stream, calling handle for each event, until
+// the stream ends, the reader fails or handle returns an error
+func readSSE(r io.Reader, handle func(sseEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var event sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line dispatches the event built up so far
+		if line == "" {
+			if len(data) > 0 || event.ID != "" {
+				event.Data = strings.Join(data, "\n")
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				if err := handle(event); err != nil {
+					return err
+				}
+			}
+			event = sseEvent{}
+			data = nil
+			continue
+		}
+
+		// Lines starting with a colon are comments, often used as keep-alives
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			event.ID = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
@@ -0,0 +1,311 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sammcj/gomcp/config"
+)
+
+// fakeSSEServer is an MCP server speaking the HTTP+SSE transport. Each GET
+// opens a session whose endpoint is announced as a relative URL, and the
+// responses to messages POSTed there are sent on that session's stream.
+type fakeSSEServer struct {
+	mu       sync.Mutex
+	sessions map[string]chan []byte
+	streams  int
+	inits    int
+	drop     chan struct{} // closed to end every open stream
+}
+
+func newFakeSSEServer() *fakeSSEServer {
+	return &fakeSSEServer{
+		sessions: make(map[string]chan []byte),
+		drop:     make(chan struct{}),
+	}
+}
+
+func (f *fakeSSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/mcp/sse":
+		f.mu.Lock()
+		f.streams++
+		session := fmt.Sprint(f.streams)
+		messages := make(chan []byte, 16)
+		f.sessions[session] = messages
+		drop := f.drop
+		f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": connected\n\nevent: endpoint\ndata: messages?session=%s\n\n", session)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case msg := <-messages:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+				w.(http.Flusher).Flush()
+			case <-drop:
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+	case r.Method == http.MethodPost && r.URL.Path == "/mcp/messages":
+		f.mu.Lock()
+		messages, ok := f.sessions[r.URL.Query().Get("session")]
+		f.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		if msg.ID == nil {
+			return
+		}
+
+		var result interface{} = map[string]interface{}{}
+		switch msg.Method {
+		case "initialize":
+			f.mu.Lock()
+			f.inits++
+			f.mu.Unlock()
+			result = map[string]interface{}{
+				"protocolVersion": latestProtocolVersion,
+				"capabilities":    map[string]interface{}{},
+				"serverInfo":      map[string]interface{}{"name": "fake", "version": "1"},
+			}
+		case "echo":
+			result = msg.Params
+		}
+		resp, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+		messages <- resp
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// dropStreams ends every open event stream, as a restarting server would
+func (f *fakeSSEServer) dropStreams() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	close(f.drop)
+	f.drop = make(chan struct{})
+}
+
+// initializations returns how many times clients have initialized
+func (f *fakeSSEServer) initializations() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inits
+}
+
+// startSSEClient connects a client to a fake SSE server
+func startSSEClient(t *testing.T, f *fakeSSEServer) (*MCPClient, *sseTransport) {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	transport := newSSETransport(config.MCPServerConfig{Name: "remote", URL: srv.URL + "/mcp/sse"}, testLogger)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := transport.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	client := newMCPClient(transport, testLogger)
+	t.Cleanup(func() { client.Close() })
+	return client, transport
+}
+
+func TestSSETransportResolvesEndpoint(t *testing.T) {
+	_, transport := startSSEClient(t, newFakeSSEServer())
+
+	endpoint, err := transport.currentEndpoint(context.Background())
+	if err != nil {
+		t.Fatalf("currentEndpoint: %v", err)
+	}
+	want := strings.TrimSuffix(transport.url, "/sse") + "/messages?session=1"
+	if endpoint != want {
+		t.Errorf("endpoint = %q, want %q", endpoint, want)
+	}
+}
+
+func TestSSETransportResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+	}{
+		{"relative", "messages?session=1", "http://host:8080/mcp/messages?session=1"},
+		{"absolute path", "/messages?session=1", "http://host:8080/messages?session=1"},
+		{"parent", "../messages", "http://host:8080/messages"},
+		{"absolute URL", "https://other/messages", "https://other/messages"},
+		{"surrounding space", " /messages \n", "http://host:8080/messages"},
+	}
+	transport := &sseTransport{url: "http://host:8080/mcp/sse"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transport.resolve(tt.endpoint)
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolve(%q) = %q, want %q", tt.endpoint, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSETransportCall(t *testing.T) {
+	client, _ := startSSEClient(t, newFakeSSEServer())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := client.call(ctx, "echo", map[string]interface{}{"v": "hello"})
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	var envelope struct {
+		Result map[string]interface{} `json:"result"`
+	}
+	if err := json.Unmarshal(resp, &envelope); err != nil {
+		t.Fatalf("invalid response %s: %v", resp, err)
+	}
+	if envelope.Result["v"] != "hello" {
+		t.Errorf("result = %v, want the params echoed", envelope.Result)
+	}
+}
+
+func TestSSETransportReconnects(t *testing.T) {
+	f := newFakeSSEServer()
+	client, transport := startSSEClient(t, f)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := client.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+
+	f.dropStreams()
+
+	// The endpoint of the dropped stream is forgotten straight away
+	deadline := time.Now().Add(time.Second)
+	for {
+		transport.mu.Lock()
+		endpoint := transport.endpoint
+		transport.mu.Unlock()
+		if endpoint == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("endpoint of the dropped stream was kept")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The reconnected stream announces a new session, which is initialized again
+	deadline = time.Now().Add(5 * time.Second)
+	for f.initializations() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not re-initialize after reconnecting")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	endpoint, err := transport.currentEndpoint(ctx)
+	if err != nil {
+		t.Fatalf("currentEndpoint: %v", err)
+	}
+	if !strings.HasSuffix(endpoint, "session=2") {
+		t.Errorf("endpoint = %q, want the second session's", endpoint)
+	}
+	if _, err := client.call(ctx, "echo", map[string]interface{}{}); err != nil {
+		t.Errorf("call after reconnect: %v", err)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []sseEvent
+	}{
+		{
+			name:   "message",
+			stream: "event: message\ndata: {}\n\n",
+			want:   []sseEvent{{Event: "message", Data: "{}"}},
+		},
+		{
+			name:   "default event type",
+			stream: "data: {}\n\n",
+			want:   []sseEvent{{Event: "message", Data: "{}"}},
+		},
+		{
+			name:   "comments",
+			stream: ": keep-alive\n\n:another\nevent: endpoint\n: ignored\ndata: /messages\n\n",
+			want:   []sseEvent{{Event: "endpoint", Data: "/messages"}},
+		},
+		{
+			name:   "multi-line data",
+			stream: "data: {\ndata:  \"a\": 1\ndata: }\n\n",
+			want:   []sseEvent{{Event: "message", Data: "{\n \"a\": 1\n}"}},
+		},
+		{
+			name:   "id",
+			stream: "id: 7\ndata: one\n\nid: 8\ndata: two\n\n",
+			want:   []sseEvent{{ID: "7", Event: "message", Data: "one"}, {ID: "8", Event: "message", Data: "two"}},
+		},
+		{
+			name:   "id without data",
+			stream: "id: 9\n\n",
+			want:   []sseEvent{{ID: "9", Event: "message"}},
+		},
+		{
+			name:   "unterminated event",
+			stream: "data: one\n\ndata: two\n",
+			want:   []sseEvent{{Event: "message", Data: "one"}},
+		},
+		{
+			name:   "no space after colon",
+			stream: "event:endpoint\ndata:/messages\n\n",
+			want:   []sseEvent{{Event: "endpoint", Data: "/messages"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []sseEvent
+			err := readSSE(strings.NewReader(tt.stream), func(event sseEvent) error {
+				got = append(got, event)
+				return nil
+			})
+			if err != io.EOF {
+				t.Errorf("readSSE returned %v, want io.EOF", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package bridge
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sammcj/gomcp/config"
+)
+
+// stdioTransport runs an MCP server as a child process and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout
+type stdioTransport struct {
+	cfg      config.MCPServerConfig
+	logger   *log.Logger
+	logLevel string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	stdout   io.ReadCloser
+	writing  chan struct{} // holds a token while a message is written to stdin
+	messages chan []byte
+
+	stderr     *lineBuffer
+	stderrDone chan struct{} // closed once stderr has been read to the end
+	ignore     []*regexp.Regexp
+
+	exited  chan struct{} // closed once the process has exited
+	exitErr error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// newStdioTransport creates a transport for the server command in cfg. Its
+// stderr lines are logged if the server's stderr level passes logLevel.
+func newStdioTransport(cfg config.MCPServerConfig, logger *log.Logger, logLevel string) *stdioTransport {
+	return &stdioTransport{
+		cfg:        cfg,
+		logger:     logger,
+		logLevel:   logLevel,
+		messages:   make(chan []byte, 16),
+		writing:    make(chan struct{}, 1),
+		stderr:     newLineBuffer(cfg.StderrBufferLines()),
+		stderrDone: make(chan struct{}),
+		ignore:     compilePatterns(cfg.LogIgnore),
+		exited:     make(chan struct{}),
+	}
+}
+
+// Start launches the server process
+func (t *stdioTransport) Start(ctx context.Context) error {
+	t.logger.Printf("Starting MCP server with command: %s %v", t.cfg.Command, t.cfg.Arguments)
+
+	cmd := exec.Command(t.cfg.Command, t.cfg.Arguments...)
+	cmd.Dir = t.cfg.Cwd
+	cmd.Env = serverEnv(t.cfg)
+	setProcessGroup(cmd)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.logger.Printf("Failed to create stdin pipe: %v", err)
+		return fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.logger.Printf("Failed to create stdout pipe: %v", err)
+		stdin.Close()
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.logger.Printf("Failed to create stderr pipe: %v", err)
+		stdin.Close()
+		stdout.Close()
+		return fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	t.logger.Printf("Starting MCP server process...")
+	if err := cmd.Start(); err != nil {
+		t.logger.Printf("Failed to start MCP server: %v", err)
+		stdin.Close()
+		stdout.Close()
+		stderr.Close()
+		return fmt.Errorf("failed to start command: %w", err)
+	}
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.stdout = stdout
+
+	// Start reading messages and stderr in goroutines
+	go t.readStderr(stderr)
+	go t.readMessages()
+
+	t.logger.Printf("MCP server process started with PID: %d", cmd.Process.Pid)
+	return nil
+}
+
+// readMessages forwards each JSON line the server writes until stdout closes,
+// then reaps the process
+func (t *stdioTransport) readMessages() {
+	defer close(t.messages)
+	defer t.wait()
+
+	reader := bufio.NewReader(t.stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if err != io.EOF {
+				t.logger.Printf("Error reading response: %v", err)
+			}
+			return
+		}
+
+		// Skip non-JSON lines (e.g., pnpm startup messages)
+		if !json.Valid(line) {
+			continue
+		}
+		t.messages <- line
+	}
+}
+
+// readStderr logs each line the server writes to stderr and keeps the most
+// recent ones to explain failures
+func (t *stdioTransport) readStderr(stderr io.Reader) {
+	defer close(t.stderrDone)
+
+	level := t.cfg.StderrLogLevel()
+	logged := levelEnabled(t.logLevel, level)
+
+	scanner := bufio.NewScanner(stderr)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		t.stderr.Add(line)
+		if logged && !matchesAny(t.ignore, line) {
+			t.logger.Printf("[%s] [%s] %s", t.cfg.Name, level, line)
+		}
+	}
+}
+
+// wait waits for the process to exit and records how it ended. Stderr must
+// be drained first, since Wait closes the pipe.
+func (t *stdioTransport) wait() {
+	<-t.stderrDone
+	t.exitErr = t.cmd.Wait()
+	close(t.exited)
+}
+
+// RecentStderr returns up to n of the last lines the server wrote to stderr
+func (t *stdioTransport) RecentStderr(n int) []string {
+	return t.stderr.Last(n)
+}
+
+// ExitStatus returns the exit code of the server process and the end of its
+// stderr output. It blocks until the process has exited.
+func (t *stdioTransport) ExitStatus() (int, string) {
+	<-t.exited
+
+	code := 0
+	if t.exitErr != nil {
+		code = -1
+		var exitErr *exec.ExitError
+		if errors.As(t.exitErr, &exitErr) {
+			code = exitErr.ExitCode()
+		}
+	}
+	return code, strings.Join(t.stderr.Last(0), "\n")
+}
+
+// Messages returns the channel of messages read from the server's stdout
+func (t *stdioTransport) Messages() <-chan []byte {
+	return t.messages
+}
+
+// Send writes a message to the server's stdin. A server that stops reading
+// fills the pipe, so Send gives up when ctx ends rather than wait for room.
+func (t *stdioTransport) Send(ctx context.Context, msg []byte) error {
+	select {
+	case t.writing <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to write request: %w", ctx.Err())
+	}
+
+	// A write can't be interrupted, so it finishes in the background if ctx
+	// ends first, keeping the token so messages are never interleaved.
+	// Closing stdin ends a write that never finishes.
+	done := make(chan error, 1)
+	go func() {
+		_, err := t.stdin.Write(append(msg, '\n'))
+		<-t.writing
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to write request: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to write request: %w", ctx.Err())
+	}
+}
+
+// Close stops the server process in stages: closing stdin asks it to exit,
+// then its process group is sent SIGTERM and finally SIGKILL, each after the
+// server's shutdown grace period. It waits for the process to be reaped.
+func (t *stdioTransport) Close() error {
+	if t.cmd == nil {
+		return nil
+	}
+	t.closeOnce.Do(func() {
+		t.closeErr = t.shutdown()
+	})
+	return t.closeErr
+}
+
+// shutdown runs the stages of Close and logs how the server exited
+func (t *stdioTransport) shutdown() error {
+	// A server that exited by itself has already been reported
+	select {
+	case <-t.exited:
+		t.stdin.Close()
+		return nil
+	default:
+	}
+
+	grace := t.cfg.ShutdownGracePeriod()
+	name := t.cfg.Name
+
+	// Most servers exit by themselves once their input ends
+	if err := t.stdin.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		t.logger.Printf("Failed to close stdin of %s: %v", name, err)
+	}
+	if t.waitExit(grace) {
+		t.logExit("after closing stdin")
+		return nil
+	}
+
+	t.logger.Printf("MCP server %s still running %s after closing stdin; sending SIGTERM", name, grace)
+	if err := terminateProcessGroup(t.cmd.Process); err != nil {
+		t.logger.Printf("Failed to send SIGTERM to %s: %v", name, err)
+	}
+	if t.waitExit(grace) {
+		t.logExit("after SIGTERM")
+		return nil
+	}
+
+	t.logger.Printf("MCP server %s still running %s after SIGTERM; sending SIGKILL", name, grace)
+	if err := killProcessGroup(t.cmd.Process); err != nil {
+		t.logger.Printf("Failed to send SIGKILL to %s: %v", name, err)
+		return fmt.Errorf("failed to kill process: %w", err)
+	}
+	if t.waitExit(grace) {
+		t.logExit("after SIGKILL")
+		return nil
+	}
+
+	// Something outside the process group is holding the server's output open
+	return fmt.Errorf("MCP server %s did not exit after SIGKILL", name)
+}
+
+// waitExit waits up to timeout for the process to exit and be reaped
+func (t *stdioTransport) waitExit(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-t.exited:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// logExit reports how the stopped server exited
+func (t *stdioTransport) logExit(stage string) {
+	t.logger.Printf("MCP server %s exited %s: %s", t.cfg.Name, stage, t.cmd.ProcessState)
+}
+
+// serverEnv builds the environment for a server process. Values from cfg.Env
+// take precedence over inherited ones.
+func serverEnv(cfg config.MCPServerConfig) []string {
+	var base []string
+	if cfg.CleanEnv {
+		for _, key := range []string{"PATH", "HOME"} {
+			if value, ok := os.LookupEnv(key); ok {
+				base = append(base, key+"="+value)
+			}
+		}
+	} else {
+		base = os.Environ()
+	}
+
+	// Unbuffered output keeps Python servers from holding back responses
+	defaults := map[string]string{"PYTHONUNBUFFERED": "1"}
+
+	env := make([]string, 0, len(base)+len(defaults)+len(cfg.Env))
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, overridden := cfg.Env[key]; overridden {
+			continue
+		}
+		delete(defaults, key)
+		env = append(env, kv)
+	}
+	for key, value := range defaults {
+		if _, overridden := cfg.Env[key]; !overridden {
+			env = append(env, key+"="+value)
+		}
+	}
+	for key, value := range cfg.Env {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
@@ -0,0 +1,428 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sammcj/gomcp/config"
+)
+
+const (
+	// sessionHeader carries the session ID a streamable HTTP server assigns during initialize
+	sessionHeader = "Mcp-Session-Id"
+
+	// maxResumeAttempts bounds how often an interrupted stream is resumed before giving up
+	maxResumeAttempts = 5
+)
+
+// streamableTransport connects to a remote MCP server using the streamable
+// HTTP transport. Every client message is POSTed to a single endpoint, which
+// answers with either a JSON body or an SSE stream. A separate GET stream
+// carries messages the server sends on its own. Interrupted streams are
+// resumed with Last-Event-ID so the server can replay what was missed.
+type streamableTransport struct {
+	url      string
+	headers  map[string]string
+	logger   *log.Logger
+	client   *http.Client
+	messages chan []byte
+
+	mu            sync.Mutex
+	sessionID     string
+	sessionCancel context.CancelFunc // stops the GET stream of the current session
+	closed        bool
+	onReconnect   func()
+	wg            sync.WaitGroup // tracks goroutines that write to messages
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// outgoingMessage holds the fields of a client message the transport cares about
+type outgoingMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// newStreamableTransport creates a transport for the server URL in cfg
+func newStreamableTransport(cfg config.MCPServerConfig, logger *log.Logger) *streamableTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &streamableTransport{
+		url:      cfg.URL,
+		headers:  cfg.Headers,
+		logger:   logger,
+		client:   &http.Client{},
+		messages: make(chan []byte, 16),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start prepares the transport. The connection itself is made by the first
+// message sent, which is the initialize request.
+func (t *streamableTransport) Start(ctx context.Context) error {
+	t.logger.Printf("Using streamable HTTP transport for MCP server at %s", t.url)
+	return nil
+}
+
+// Send POSTs a message and consumes whatever the server answers with
+func (t *streamableTransport) Send(ctx context.Context, msg []byte) error {
+	var out outgoingMessage
+	if err := json.Unmarshal(msg, &out); err != nil {
+		return fmt.Errorf("failed to inspect message: %w", err)
+	}
+
+	reqCtx, cancel := t.requestContext(ctx)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, t.url, bytes.NewReader(msg))
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	sessionID := t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if id := resp.Header.Get(sessionHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && sessionID != "":
+		resp.Body.Close()
+		cancel()
+		t.expireSession(sessionID)
+		return fmt.Errorf("session %s expired", sessionID)
+
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case resp.StatusCode == http.StatusAccepted:
+		resp.Body.Close()
+		cancel()
+
+	case mediaType == "text/event-stream":
+		// Responses arrive on the stream after Send returns, so read it in the background
+		if !t.track() {
+			resp.Body.Close()
+			cancel()
+			return fmt.Errorf("transport closed")
+		}
+		go func() {
+			defer t.wg.Done()
+			defer cancel()
+			t.readResponseStream(reqCtx, resp.Body, out.ID)
+		}()
+
+	default:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if len(bytes.TrimSpace(body)) > 0 && t.track() {
+			t.deliver(body)
+			t.wg.Done()
+		}
+	}
+
+	// Once initialized, listen for messages the server sends outside of responses
+	if out.Method == "notifications/initialized" {
+		t.listen()
+	}
+	return nil
+}
+
+// readResponseStream consumes the SSE stream answering a POST. If the stream
+// breaks before the response arrives it is resumed from the last event seen.
+func (t *streamableTransport) readResponseStream(ctx context.Context, body io.ReadCloser, requestID json.RawMessage) {
+	lastEventID, complete, err := t.consume(body, requestID)
+	body.Close()
+	if complete || ctx.Err() != nil {
+		return
+	}
+	if lastEventID == "" {
+		t.logger.Printf("Response stream from %s ended early and cannot be resumed: %v", t.url, err)
+		return
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= maxResumeAttempts; attempt++ {
+		t.logger.Printf("Resuming response stream from %s after event %s (attempt %d/%d)",
+			t.url, lastEventID, attempt, maxResumeAttempts)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		resp, err := t.openStream(ctx, lastEventID)
+		if err != nil {
+			t.logger.Printf("Failed to resume stream: %v", err)
+			// A status code means the server refused to resume, rather than being unreachable
+			if resp != nil {
+				return
+			}
+			backoff = min(backoff*2, maxReconnectBackoff)
+			continue
+		}
+
+		id, complete, _ := t.consume(resp.Body, requestID)
+		resp.Body.Close()
+		if complete || ctx.Err() != nil {
+			return
+		}
+		if id != "" {
+			lastEventID = id
+			backoff = time.Second
+		}
+	}
+	t.logger.Printf("Giving up on response stream from %s", t.url)
+}
+
+// listen opens the GET stream for server-initiated messages for the current session
+func (t *streamableTransport) listen() {
+	t.mu.Lock()
+	if t.sessionCancel != nil {
+		t.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(t.ctx)
+	t.sessionCancel = cancel
+	t.mu.Unlock()
+
+	if !t.track() {
+		cancel()
+		return
+	}
+	go func() {
+		defer t.wg.Done()
+
+		lastEventID := ""
+		backoff := time.Second
+		for ctx.Err() == nil {
+			resp, err := t.openStream(ctx, lastEventID)
+			if err != nil {
+				switch {
+				case resp != nil && resp.StatusCode == http.StatusMethodNotAllowed:
+					t.logger.Printf("MCP server at %s does not offer a stream for server-initiated messages", t.url)
+					return
+				case resp != nil && resp.StatusCode == http.StatusNotFound:
+					t.expireSession(resp.Request.Header.Get(sessionHeader))
+					return
+				}
+				if ctx.Err() == nil {
+					t.logger.Printf("Failed to open stream from %s: %v", t.url, err)
+				}
+			} else {
+				id, _, _ := t.consume(resp.Body, nil)
+				resp.Body.Close()
+				if id != "" {
+					lastEventID = id
+				}
+				backoff = time.Second
+			}
+
+			// The server may close the stream at any time, so keep it open
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxReconnectBackoff)
+		}
+	}()
+}
+
+// openStream issues a GET for an SSE stream, resuming after lastEventID if set.
+// On a non-200 status the response is returned alongside the error.
+func (t *streamableTransport) openStream(ctx context.Context, lastEventID string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", t.url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return resp, nil
+}
+
+// consume delivers the messages on an SSE stream until it ends. It reports the
+// last event ID seen and whether the response to requestID was among them.
+func (t *streamableTransport) consume(body io.Reader, requestID json.RawMessage) (string, bool, error) {
+	var lastEventID string
+	var complete bool
+	err := readSSE(body, func(event sseEvent) error {
+		if event.ID != "" {
+			lastEventID = event.ID
+		}
+		if event.Data == "" {
+			return nil
+		}
+		if isResponseTo([]byte(event.Data), requestID) {
+			complete = true
+		}
+		t.deliver([]byte(event.Data))
+		return nil
+	})
+	return lastEventID, complete, err
+}
+
+// deliver passes a message received from the server on to the client
+func (t *streamableTransport) deliver(data []byte) {
+	if !json.Valid(data) {
+		t.logger.Printf("Skipping invalid JSON message from %s", t.url)
+		return
+	}
+	select {
+	case t.messages <- data:
+	case <-t.ctx.Done():
+	}
+}
+
+// expireSession forgets a session the server no longer recognises and asks
+// the client to initialize a new one
+func (t *streamableTransport) expireSession(sessionID string) {
+	t.mu.Lock()
+	if t.sessionID != sessionID {
+		t.mu.Unlock()
+		return
+	}
+	t.logger.Printf("Session %s with %s expired, starting a new one", sessionID, t.url)
+	t.sessionID = ""
+	if t.sessionCancel != nil {
+		t.sessionCancel()
+		t.sessionCancel = nil
+	}
+	handler := t.onReconnect
+	t.mu.Unlock()
+
+	if handler != nil {
+		go handler()
+	}
+}
+
+// requestContext returns a context that ends with either ctx or the transport
+func (t *streamableTransport) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	reqCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(t.ctx, cancel)
+	return reqCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// track registers a goroutine that writes to messages, unless the transport is closed
+func (t *streamableTransport) track() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return false
+	}
+	t.wg.Add(1)
+	return true
+}
+
+// setHeaders applies the configured headers and session ID, returning the session ID used
+func (t *streamableTransport) setHeaders(req *http.Request) string {
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID != "" {
+		req.Header.Set(sessionHeader, sessionID)
+	}
+	return sessionID
+}
+
+// Messages returns the channel of messages received from the server
+func (t *streamableTransport) Messages() <-chan []byte {
+	return t.messages
+}
+
+// SetReconnectHandler registers a function to run when the session has to be re-established
+func (t *streamableTransport) SetReconnectHandler(handler func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onReconnect = handler
+}
+
+// Close ends the session and stops all streams
+func (t *streamableTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	sessionID := t.sessionID
+	t.mu.Unlock()
+
+	// Tell the server the session is over; servers that don't allow this answer 405
+	if sessionID != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+		if err == nil {
+			t.setHeaders(req)
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+		cancel()
+	}
+
+	t.cancel()
+	t.wg.Wait()
+	close(t.messages)
+	return nil
+}
+
+// isResponseTo reports whether data is the JSON-RPC response to the request with the given ID
+func isResponseTo(data []byte, requestID json.RawMessage) bool {
+	if len(requestID) == 0 {
+		return false
+	}
+	var msg outgoingMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return false
+	}
+	return msg.Method == "" && bytes.Equal(bytes.TrimSpace(msg.ID), bytes.TrimSpace(requestID))
+}
@@ -0,0 +1,315 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sammcj/gomcp/config"
+)
+
+// fakeStreamableServer is an MCP server speaking the streamable HTTP
+// transport. Each initialize starts a new session. An echo request is
+// answered the way its "reply" parameter asks: as a JSON body, on an SSE
+// stream, or on a stream that breaks before the response and has to be
+// resumed with Last-Event-ID.
+type fakeStreamableServer struct {
+	mu sync.Mutex
+	streamableRecord
+	replay map[string][]byte // responses held back from broken streams, by event ID
+}
+
+// streamableRecord is what a fakeStreamableServer has seen
+type streamableRecord struct {
+	session     string
+	inits       int
+	sessionIDs  []string // Mcp-Session-Id sent with each POST after initialize
+	lastEventID []string // Last-Event-ID sent with each resumed stream
+	deleted     []string // sessions ended with DELETE
+}
+
+func newFakeStreamableServer() *fakeStreamableServer {
+	return &fakeStreamableServer{replay: make(map[string][]byte)}
+}
+
+func (f *fakeStreamableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	session := f.session
+	f.mu.Unlock()
+	sessionID := r.Header.Get(sessionHeader)
+
+	switch r.Method {
+	case http.MethodDelete:
+		f.mu.Lock()
+		f.deleted = append(f.deleted, sessionID)
+		f.mu.Unlock()
+		return
+
+	case http.MethodGet:
+		if sessionID != session {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		if id := r.Header.Get("Last-Event-ID"); id != "" {
+			f.mu.Lock()
+			f.lastEventID = append(f.lastEventID, id)
+			resp := f.replay[id]
+			f.mu.Unlock()
+			fmt.Fprintf(w, "id: %s-resumed\ndata: %s\n\n", id, resp)
+			return
+		}
+		// The stream for server-initiated messages stays open but quiet
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		return
+	}
+
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			Reply string `json:"reply"`
+		} `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if msg.Method == "initialize" {
+		f.mu.Lock()
+		f.inits++
+		f.session = fmt.Sprintf("session-%d", f.inits)
+		w.Header().Set(sessionHeader, f.session)
+		f.mu.Unlock()
+		writeJSONResponse(w, msg.ID, map[string]interface{}{
+			"protocolVersion": latestProtocolVersion,
+			"capabilities":    map[string]interface{}{},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "1"},
+		})
+		return
+	}
+
+	f.mu.Lock()
+	f.sessionIDs = append(f.sessionIDs, sessionID)
+	f.mu.Unlock()
+	if sessionID != session {
+		http.NotFound(w, r)
+		return
+	}
+	if msg.ID == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	resp, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      msg.ID,
+		"result":  map[string]interface{}{"reply": msg.Params.Reply},
+	})
+	switch msg.Params.Reply {
+	case "stream":
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "id: 1\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{}}\n\nid: 2\ndata: %s\n\n", resp)
+
+	case "broken stream":
+		// The stream ends before the response, which is kept for the resume
+		eventID := fmt.Sprintf("event-%s", msg.ID)
+		f.mu.Lock()
+		f.replay[eventID] = resp
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "id: %s\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{}}\n\n", eventID)
+
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}
+}
+
+// writeJSONResponse answers a request with a JSON-RPC result body
+func writeJSONResponse(w http.ResponseWriter, id json.RawMessage, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+// expire makes the server forget the current session, as a restarted server would
+func (f *fakeStreamableServer) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session = ""
+}
+
+// snapshot returns a copy of the server's records under its lock
+func (f *fakeStreamableServer) snapshot() streamableRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return streamableRecord{
+		session:     f.session,
+		inits:       f.inits,
+		sessionIDs:  append([]string(nil), f.sessionIDs...),
+		lastEventID: append([]string(nil), f.lastEventID...),
+		deleted:     append([]string(nil), f.deleted...),
+	}
+}
+
+// startStreamableClient connects and initializes a client against a fake
+// streamable HTTP server
+func startStreamableClient(t *testing.T, f *fakeStreamableServer) *MCPClient {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	transport := newStreamableTransport(config.MCPServerConfig{Name: "remote", URL: srv.URL + "/mcp"}, testLogger)
+	if err := transport.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	client := newMCPClient(transport, testLogger)
+	t.Cleanup(func() { client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Initialize(ctx, mcp.InitializeRequest{}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return client
+}
+
+// echoReply calls the fake server's echo method and returns the reply it reports
+func echoReply(ctx context.Context, client *MCPClient, reply string) (string, error) {
+	resp, err := client.call(ctx, "echo", map[string]interface{}{"reply": reply})
+	if err != nil {
+		return "", err
+	}
+	var envelope struct {
+		Result struct {
+			Reply string `json:"reply"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(resp, &envelope); err != nil {
+		return "", err
+	}
+	return envelope.Result.Reply, nil
+}
+
+func TestStreamableTransportSession(t *testing.T) {
+	f := newFakeStreamableServer()
+	client := startStreamableClient(t, f)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := echoReply(ctx, client, "json"); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	// Both notifications/initialized and the call carry the session from initialize
+	got := f.snapshot().sessionIDs
+	if len(got) != 2 {
+		t.Fatalf("server saw %d messages after initialize, want 2", len(got))
+	}
+	for n, id := range got {
+		if id != "session-1" {
+			t.Errorf("message %d sent session %q, want session-1", n, id)
+		}
+	}
+}
+
+func TestStreamableTransportReplies(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+	}{
+		{"JSON body", "json"},
+		{"event stream", "stream"},
+	}
+	f := newFakeStreamableServer()
+	client := startStreamableClient(t, f)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			got, err := echoReply(ctx, client, tt.reply)
+			if err != nil {
+				t.Fatalf("call: %v", err)
+			}
+			if got != tt.reply {
+				t.Errorf("reply = %q, want %q", got, tt.reply)
+			}
+		})
+	}
+}
+
+func TestStreamableTransportResumesStream(t *testing.T) {
+	f := newFakeStreamableServer()
+	client := startStreamableClient(t, f)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, err := echoReply(ctx, client, "broken stream")
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if got != "broken stream" {
+		t.Errorf("reply = %q, want the response replayed on the resumed stream", got)
+	}
+
+	resumed := f.snapshot().lastEventID
+	if len(resumed) != 1 || !strings.HasPrefix(resumed[0], "event-") {
+		t.Errorf("resumed with Last-Event-ID %q, want the ID of the last event on the broken stream", resumed)
+	}
+}
+
+func TestStreamableTransportExpiredSession(t *testing.T) {
+	f := newFakeStreamableServer()
+	client := startStreamableClient(t, f)
+
+	f.expire()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := echoReply(ctx, client, "json"); err == nil {
+		t.Fatal("call on an expired session succeeded")
+	}
+
+	// The 404 makes the client initialize a new session in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for f.snapshot().session != "session-2" {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not initialize a new session")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	got, err := echoReply(ctx, client, "json")
+	if err != nil {
+		t.Fatalf("call on the new session: %v", err)
+	}
+	if got != "json" {
+		t.Errorf("reply = %q, want json", got)
+	}
+	sessionIDs := f.snapshot().sessionIDs
+	if last := sessionIDs[len(sessionIDs)-1]; last != "session-2" {
+		t.Errorf("call sent session %q, want session-2", last)
+	}
+}
+
+func TestStreamableTransportCloseEndsSession(t *testing.T) {
+	f := newFakeStreamableServer()
+	client := startStreamableClient(t, f)
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	deleted := f.snapshot().deleted
+	if len(deleted) != 1 || deleted[0] != "session-1" {
+		t.Errorf("DELETE sent for sessions %q, want [session-1]", deleted)
+	}
+}
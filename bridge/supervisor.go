@@ -0,0 +1,557 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/sammcj/gomcp/config"
+	"github.com/sammcj/gomcp/types"
+)
+
+// ServerState describes how an MCP server is doing
+type ServerState string
+
+// States an MCP server moves through
+const (
+	// StateStarting means the server is being launched or initialized
+	StateStarting ServerState = "starting"
+	// StateReady means the server is connected and its tools are registered
+	StateReady ServerState = "ready"
+	// StateDegraded means the server stopped unexpectedly and is waiting to be restarted
+	StateDegraded ServerState = "degraded"
+	// StateCrashed means the server stopped and will not be restarted
+	StateCrashed ServerState = "crashed"
+	// StateUnhealthy means the server is connected but has stopped answering pings
+	StateUnhealthy ServerState = "unhealthy"
+	// StateIdle means a lazy server isn't running; it starts when one of its tools is called
+	StateIdle ServerState = "idle"
+)
+
+// restartResetAfter is how long a restarted server must stay up before its
+// restart count starts again from zero
+const restartResetAfter = time.Minute
+
+// maxRestartBackoff caps the delay between attempts to restart a crashed server
+const maxRestartBackoff = 30 * time.Second
+
+// ServerStatus reports the state of an MCP server
+type ServerStatus struct {
+	Name         string      `json:"name"`
+	Required     bool        `json:"required"` // whether the bridge needs the server, per its configuration
+	State        ServerState `json:"state"`
+	Since        time.Time   `json:"since"`
+	Restarts     int         `json:"restarts"`
+	ExitCode     *int        `json:"exit_code,omitempty"`
+	Stderr       string      `json:"stderr,omitempty"`
+	LastError    string      `json:"last_error,omitempty"`
+	LatencyMs    float64     `json:"latency_ms,omitempty"`    // round trip of the last successful ping
+	PingFailures int         `json:"ping_failures,omitempty"` // pings failed in a row
+}
+
+// supervisor keeps one MCP server running, restarting it with backoff when
+// its connection ends unexpectedly. Lazy servers are instead started on
+// demand and stopped again when idle.
+type supervisor struct {
+	bridge *Bridge
+	cfg    config.MCPServerConfig
+
+	mu       sync.Mutex
+	status   ServerStatus
+	readyAt  time.Time
+	client   *MCPClient // the running client, nil while the server is down
+	active   int        // tool calls in progress
+	lastUsed time.Time
+
+	startMu sync.Mutex // serialises starting a lazy server
+}
+
+// newSupervisor creates a supervisor for the server in cfg
+func newSupervisor(b *Bridge, cfg config.MCPServerConfig) *supervisor {
+	return &supervisor{
+		bridge: b,
+		cfg:    cfg,
+		status: ServerStatus{Name: cfg.Name, Required: cfg.IsRequired(), State: StateStarting, Since: time.Now()},
+	}
+}
+
+// start connects to the server for the first time and begins watching it
+func (s *supervisor) start() error {
+	client, err := s.bridge.connectServer(s.cfg)
+	if err != nil {
+		s.setState(StateCrashed, func(status *ServerStatus) {
+			status.LastError = err.Error()
+		})
+		return err
+	}
+
+	s.running(client)
+	return nil
+}
+
+// startIdle registers the cached tools of a lazy server without starting it
+func (s *supervisor) startIdle(tools []mcp.Tool) {
+	b := s.bridge
+	b.mu.Lock()
+	if b.serverTools != nil {
+		b.serverTools[s.cfg.Name] = tools
+	}
+	b.mu.Unlock()
+
+	s.setState(StateIdle, nil)
+}
+
+// running records that the server is ready on client and watches it
+func (s *supervisor) running(client *MCPClient) {
+	s.mu.Lock()
+	s.client = client
+	s.lastUsed = time.Now()
+	s.status.PingFailures = 0
+	s.mu.Unlock()
+
+	s.setState(StateReady, nil)
+	go s.watch(client)
+	go s.watchIdle(client)
+	go s.watchHealth(client)
+}
+
+// acquire returns the server's client for a tool call, starting a lazy server
+// first if it isn't running. Each successful acquire must be paired with a
+// release once the call is done.
+func (s *supervisor) acquire() (*MCPClient, error) {
+	if client := s.current(); client != nil {
+		return client, nil
+	}
+	if !s.cfg.Lazy {
+		return nil, fmt.Errorf("MCP server %s is %s", s.cfg.Name, s.Status().State)
+	}
+
+	s.startMu.Lock()
+	defer s.startMu.Unlock()
+
+	// Another call may have started the server while this one waited
+	if client := s.current(); client != nil {
+		return client, nil
+	}
+
+	b := s.bridge
+	b.logger.Printf("Starting lazy MCP server %s", s.cfg.Name)
+	s.setState(StateStarting, nil)
+	started := time.Now()
+	client, err := b.connectServer(s.cfg)
+	if err != nil {
+		// Stay idle so the next call tries again
+		s.setState(StateIdle, func(status *ServerStatus) {
+			status.LastError = err.Error()
+		})
+		return nil, err
+	}
+	b.logger.Printf("MCP server %s ready in %s", s.cfg.Name, time.Since(started).Round(time.Millisecond))
+
+	if err := b.rebuildTools(); err != nil {
+		b.logger.Printf("Failed to register tools after starting %s: %v", s.cfg.Name, err)
+	}
+	s.running(client)
+
+	if client := s.current(); client != nil {
+		return client, nil
+	}
+	return nil, fmt.Errorf("MCP server %s stopped while starting", s.cfg.Name)
+}
+
+// current returns the running client, counting a call in progress on it
+func (s *supervisor) current() *MCPClient {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		s.active++
+		s.lastUsed = time.Now()
+	}
+	return s.client
+}
+
+// release marks a call taken with acquire as done
+func (s *supervisor) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	s.lastUsed = time.Now()
+}
+
+// watchIdle stops a lazy server once it has gone its idle timeout without a tool call
+func (s *supervisor) watchIdle(client *MCPClient) {
+	timeout := s.cfg.IdleTimeout
+	if !s.cfg.Lazy || timeout <= 0 {
+		return
+	}
+
+	b := s.bridge
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-client.Done():
+			return
+		case <-b.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		if s.client != client {
+			s.mu.Unlock()
+			return
+		}
+		if s.active > 0 {
+			s.mu.Unlock()
+			timer.Reset(timeout)
+			continue
+		}
+		if remaining := timeout - time.Since(s.lastUsed); remaining > 0 {
+			s.mu.Unlock()
+			timer.Reset(remaining)
+			continue
+		}
+		s.client = nil
+		s.mu.Unlock()
+
+		b.logger.Printf("Stopping MCP server %s after %s without a tool call", s.cfg.Name, timeout)
+		b.disconnectServer(s.cfg.Name)
+		if err := client.Close(); err != nil {
+			b.logger.Printf("Failed to stop MCP server %s: %v", s.cfg.Name, err)
+		}
+		if err := b.rebuildTools(); err != nil {
+			b.logger.Printf("Failed to update tools after stopping %s: %v", s.cfg.Name, err)
+		}
+		s.setState(StateIdle, nil)
+		return
+	}
+}
+
+// watchHealth pings the server on its health check interval while client is
+// running. Once enough pings fail in a row the server is unhealthy, and is
+// restarted if its health check asks for that.
+func (s *supervisor) watchHealth(client *MCPClient) {
+	check := s.cfg.HealthCheck
+	interval := check.PingInterval()
+	if interval <= 0 {
+		return
+	}
+
+	b := s.bridge
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-client.Done():
+			return
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(b.ctx, check.PingTimeout())
+		started := time.Now()
+		err := client.Ping(ctx)
+		latency := time.Since(started)
+		cancel()
+
+		// An error response still shows the server is listening
+		var rpcErr *types.RPCError
+		if errors.As(err, &rpcErr) {
+			err = nil
+		}
+
+		s.mu.Lock()
+		if s.client != client {
+			// The server was stopped while the ping was in flight
+			s.mu.Unlock()
+			return
+		}
+		if err == nil {
+			s.status.LatencyMs = float64(latency.Microseconds()) / 1000
+			s.status.PingFailures = 0
+		} else {
+			s.status.PingFailures++
+		}
+		s.mu.Unlock()
+
+		if err == nil {
+			if failures >= check.Failures() {
+				b.logger.Printf("MCP server %s is responding again", s.cfg.Name)
+				s.setState(StateReady, nil)
+			}
+			failures = 0
+			continue
+		}
+
+		failures++
+		if b.debug {
+			b.logger.Printf("Ping to MCP server %s failed (%d in a row): %v", s.cfg.Name, failures, err)
+		}
+		if failures != check.Failures() {
+			continue
+		}
+
+		b.logger.Printf("MCP server %s is unhealthy after %d failed pings: %v", s.cfg.Name, failures, err)
+		s.setState(StateUnhealthy, func(status *ServerStatus) {
+			status.LastError = err.Error()
+		})
+		if check.Restart {
+			// Closing the client hands the server to watch, which restarts it
+			b.logger.Printf("Restarting unresponsive MCP server %s", s.cfg.Name)
+			if err := client.Close(); err != nil {
+				b.logger.Printf("Failed to stop MCP server %s: %v", s.cfg.Name, err)
+			}
+			return
+		}
+	}
+}
+
+// watch waits for the client's connection to end and restarts the server if
+// that wasn't because the bridge is shutting down
+func (s *supervisor) watch(client *MCPClient) {
+	b := s.bridge
+	select {
+	case <-client.Done():
+	case <-b.ctx.Done():
+		return
+	}
+	if b.ctx.Err() != nil {
+		return
+	}
+
+	// A server stopped for being idle isn't restarted
+	s.mu.Lock()
+	if s.client != client {
+		s.mu.Unlock()
+		return
+	}
+	s.client = nil
+	s.mu.Unlock()
+
+	b.disconnectServer(s.cfg.Name)
+	if err := b.rebuildTools(); err != nil {
+		b.logger.Printf("Failed to update tools after %s stopped: %v", s.cfg.Name, err)
+	}
+
+	exitCode, stderr, local := client.ExitStatus()
+	client.Close()
+	if local {
+		b.logger.Printf("MCP server %s exited with code %d", s.cfg.Name, exitCode)
+		if stderr != "" {
+			b.logger.Printf("MCP server %s stderr before exit:\n%s", s.cfg.Name, stderr)
+		}
+	} else {
+		b.logger.Printf("Connection to MCP server %s ended", s.cfg.Name)
+	}
+
+	// A lazy server is started again by the next call that needs it
+	if s.cfg.Lazy {
+		s.setState(StateIdle, func(status *ServerStatus) {
+			if local {
+				status.ExitCode = &exitCode
+				status.Stderr = stderr
+			}
+		})
+		return
+	}
+
+	s.mu.Lock()
+	if !s.readyAt.IsZero() && time.Since(s.readyAt) > restartResetAfter {
+		s.status.Restarts = 0
+	}
+	s.mu.Unlock()
+
+	s.restart(func(status *ServerStatus) {
+		if local {
+			status.ExitCode = &exitCode
+			status.Stderr = stderr
+		}
+	})
+}
+
+// restart relaunches the server with exponential backoff until it is ready,
+// the restart limit is reached or the bridge shuts down
+func (s *supervisor) restart(recordExit func(*ServerStatus)) {
+	b := s.bridge
+	backoff := s.cfg.RestartDelay()
+	limit := s.cfg.RestartLimit()
+
+	for {
+		s.mu.Lock()
+		restarts := s.status.Restarts
+		s.mu.Unlock()
+
+		if restarts >= limit {
+			b.logger.Printf("MCP server %s crashed; giving up after %d restarts", s.cfg.Name, restarts)
+			s.setState(StateCrashed, recordExit)
+			return
+		}
+
+		b.logger.Printf("Restarting MCP server %s in %s (attempt %d/%d)", s.cfg.Name, backoff, restarts+1, limit)
+		s.setState(StateDegraded, recordExit)
+		recordExit = nil
+
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		s.setState(StateStarting, func(status *ServerStatus) {
+			status.Restarts++
+		})
+		client, err := b.connectServer(s.cfg)
+		if err != nil {
+			b.logger.Printf("Failed to restart MCP server %s: %v", s.cfg.Name, err)
+			s.mu.Lock()
+			s.status.LastError = err.Error()
+			s.mu.Unlock()
+			backoff = min(backoff*2, maxRestartBackoff)
+			continue
+		}
+
+		if err := b.rebuildTools(); err != nil {
+			b.logger.Printf("Failed to register tools after restarting %s: %v", s.cfg.Name, err)
+		}
+		b.logger.Printf("MCP server %s restarted", s.cfg.Name)
+		s.running(client)
+		return
+	}
+}
+
+// setState moves the server to a new state, applying update to the status
+// first, and tells the bridge's state handler
+func (s *supervisor) setState(state ServerState, update func(*ServerStatus)) {
+	s.mu.Lock()
+	if update != nil {
+		update(&s.status)
+	}
+	if s.status.State != state {
+		s.status.Since = time.Now()
+	}
+	s.status.State = state
+	if state == StateReady {
+		s.readyAt = s.status.Since
+		s.status.LastError = ""
+	}
+	status := s.status
+	s.mu.Unlock()
+
+	s.bridge.mu.RLock()
+	handler := s.bridge.stateChanged
+	s.bridge.mu.RUnlock()
+	if handler != nil {
+		handler(status)
+	}
+}
+
+// Status returns the current status of the server
+func (s *supervisor) Status() ServerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// startupResult records how starting one server went
+type startupResult struct {
+	name     string
+	duration time.Duration
+	idle     bool // a lazy server left to start on first use
+	err      error
+}
+
+// startupError explains an error from starting a server, naming the startup
+// timeout when that is what ran out
+func startupError(ctx context.Context, cfg config.MCPServerConfig, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("not ready within startup timeout of %s: %w", cfg.StartTimeout(), err)
+	}
+	return err
+}
+
+// formatStartupSummary renders which servers came up and how long each took
+func formatStartupSummary(results []startupResult) string {
+	var ready, idle, failed []string
+	for _, result := range results {
+		took := result.duration.Round(time.Millisecond)
+		switch {
+		case result.err != nil:
+			failed = append(failed, fmt.Sprintf("%s (after %s)", result.name, took))
+		case result.idle:
+			idle = append(idle, result.name)
+		default:
+			ready = append(ready, fmt.Sprintf("%s (%s)", result.name, took))
+		}
+	}
+
+	summary := fmt.Sprintf("MCP servers ready: %d/%d", len(ready), len(results))
+	if len(ready) > 0 {
+		summary += " - " + strings.Join(ready, ", ")
+	}
+	if len(idle) > 0 {
+		summary += "; starting on first use: " + strings.Join(idle, ", ")
+	}
+	if len(failed) > 0 {
+		summary += "; failed: " + strings.Join(failed, ", ")
+	}
+	return summary
+}
+
+// ServerStatuses returns the status of every configured MCP server, in configuration order
+func (b *Bridge) ServerStatuses() []ServerStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	statuses := make([]ServerStatus, 0, len(b.config.MCPServers))
+	for _, serverCfg := range b.config.MCPServers {
+		if s, ok := b.supervisors[serverCfg.Name]; ok {
+			statuses = append(statuses, s.Status())
+		}
+	}
+	return statuses
+}
+
+// SetServerStateHandler registers a function called whenever a server changes state
+func (b *Bridge) SetServerStateHandler(handler func(ServerStatus)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stateChanged = handler
+}
+
+// FormatServerStatuses renders server statuses as one line each
+func FormatServerStatuses(statuses []ServerStatus) string {
+	if len(statuses) == 0 {
+		return "No MCP servers configured"
+	}
+
+	var sb strings.Builder
+	for _, status := range statuses {
+		sb.WriteString(fmt.Sprintf("%s: %s since %s", status.Name, status.State, status.Since.Format(time.Kitchen)))
+		if status.Restarts > 0 {
+			sb.WriteString(fmt.Sprintf(", %d restarts", status.Restarts))
+		}
+		if status.ExitCode != nil {
+			sb.WriteString(fmt.Sprintf(", last exit code %d", *status.ExitCode))
+		}
+		if status.LatencyMs > 0 {
+			sb.WriteString(fmt.Sprintf(", ping %.1fms", status.LatencyMs))
+		}
+		if status.PingFailures > 0 {
+			sb.WriteString(fmt.Sprintf(", %d failed pings", status.PingFailures))
+		}
+		if status.LastError != "" {
+			sb.WriteString(fmt.Sprintf(", last error: %s", status.LastError))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
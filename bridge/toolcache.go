@@ -0,0 +1,101 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// cachedTools is the tool list a server offered when it last ran
+type cachedTools struct {
+	Tools   []mcp.Tool `json:"tools"`
+	Updated time.Time  `json:"updated"`
+}
+
+// toolCache keeps the tools of lazy servers on disk, so they can be offered
+// to the LLM before the servers are started
+type toolCache struct {
+	path string
+	mu   sync.Mutex
+}
+
+// newToolCache creates a cache stored in the file at path
+func newToolCache(path string) *toolCache {
+	return &toolCache{path: path}
+}
+
+// Load returns the cached tools of the named server
+func (c *toolCache) Load(server string) ([]mcp.Tool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.read()
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := entries[server]
+	return entry.Tools, ok
+}
+
+// Save records the current tools of the named server
+func (c *toolCache) Save(server string, tools []mcp.Tool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := c.read()
+	if err != nil {
+		// Start over rather than keep a cache that can't be read
+		entries = make(map[string]cachedTools)
+	}
+	entries[server] = cachedTools{Tools: tools, Updated: time.Now()}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tool cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create tool cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so a crash can't leave half a cache
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write tool cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write tool cache: %w", err)
+	}
+	return nil
+}
+
+// read loads every entry in the cache file
+func (c *toolCache) read() (map[string]cachedTools, error) {
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return make(map[string]cachedTools), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]cachedTools)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid tool cache %s: %w", c.path, err)
+	}
+	return entries, nil
+}
+
+// cacheTools saves the tools of a lazy server for the next run
+func (b *Bridge) cacheTools(serverName string, tools []mcp.Tool) {
+	if b.toolCache == nil || !b.serverConfig(serverName).Lazy {
+		return
+	}
+	if err := b.toolCache.Save(serverName, tools); err != nil {
+		b.logger.Printf("Failed to cache tools for %s: %v", serverName, err)
+	}
+}
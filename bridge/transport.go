@@ -0,0 +1,59 @@
+package bridge
+
+import (
+	"context"
+	"log"
+
+	"github.com/sammcj/gomcp/config"
+)
+
+// Transport carries JSON-RPC messages between an MCPClient and an MCP server
+type Transport interface {
+	// Start connects to the server. Messages received afterwards are
+	// delivered on the Messages channel.
+	Start(ctx context.Context) error
+
+	// Send delivers a single JSON-RPC message to the server
+	Send(ctx context.Context, msg []byte) error
+
+	// Messages returns the messages received from the server. The channel is
+	// closed once the connection has ended for good.
+	Messages() <-chan []byte
+
+	// Close disconnects from the server and releases its resources
+	Close() error
+}
+
+// reconnectingTransport is implemented by transports that re-establish a
+// dropped connection on their own. The handler runs after each reconnect,
+// since the server treats the new connection as a fresh session.
+type reconnectingTransport interface {
+	SetReconnectHandler(handler func())
+}
+
+// exitReporter is implemented by transports that run the server as a child
+// process, to explain why it stopped
+type exitReporter interface {
+	// ExitStatus returns the exit code and the end of the stderr output of a
+	// process that has exited
+	ExitStatus() (code int, stderrTail string)
+}
+
+// stderrReporter is implemented by transports that capture the server's stderr
+type stderrReporter interface {
+	// RecentStderr returns up to n of the last lines the server wrote to stderr
+	RecentStderr(n int) []string
+}
+
+// newTransport picks the transport for a server from its configuration.
+// logLevel is the bridge's logging level, which decides whether server output is logged.
+func newTransport(cfg config.MCPServerConfig, logger *log.Logger, logLevel string) Transport {
+	switch cfg.TransportType() {
+	case config.TransportStreamableHTTP:
+		return newStreamableTransport(cfg, logger)
+	case config.TransportSSE:
+		return newSSETransport(cfg, logger)
+	default:
+		return newStdioTransport(cfg, logger, logLevel)
+	}
+}
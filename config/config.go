@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,14 +15,277 @@ import (
 const (
 	defaultConfigDir  = ".config/gomcp"
 	defaultConfigFile = "config.yaml"
+	defaultToolCache  = "tool-cache.json"
+	defaultSessionsDB = "sessions.db"
+
+	// DefaultRequestTimeout bounds MCP requests for servers without an explicit timeout
+	DefaultRequestTimeout = 60 * time.Second
+
+	// DefaultMaxIterations caps the rounds of tool calls made to answer one message
+	DefaultMaxIterations = 10
+
+	// DefaultParallelToolCalls caps how many tool calls from one response run at once
+	DefaultParallelToolCalls = 4
+
+	// DefaultStartupTimeout bounds how long a server may take to complete the initialize handshake
+	DefaultStartupTimeout = 30 * time.Second
+
+	// DefaultShutdownGrace is how long a server gets to exit at each stage of shutting it down
+	DefaultShutdownGrace = 5 * time.Second
+
+	// DefaultPingInterval is how often servers are pinged to check they still respond
+	DefaultPingInterval = 30 * time.Second
+	// DefaultPingTimeout bounds each health check ping
+	DefaultPingTimeout = 10 * time.Second
+	// DefaultFailureThreshold is how many pings in a row must fail before a server is unhealthy
+	DefaultFailureThreshold = 3
+
+	// DefaultMaxRestarts is how often a crashed server is restarted before giving up
+	DefaultMaxRestarts = 5
+	// DefaultRestartBackoff is the delay before the first restart of a crashed server
+	DefaultRestartBackoff = time.Second
+
+	// DefaultStderrLevel is the level stderr output of servers is logged at
+	DefaultStderrLevel = "info"
+	// DefaultStderrLines is how many recent stderr lines are kept per server
+	DefaultStderrLines = 50
+)
+
+// Transports for connecting to MCP servers
+const (
+	TransportStdio          = "stdio"
+	TransportSSE            = "sse"
+	TransportStreamableHTTP = "streamable-http"
+)
+
+// Strategies for naming tools offered under the same name by several servers
+const (
+	// ToolNamingPrefix prefixes each clashing tool with the name of its server
+	ToolNamingPrefix = "prefix"
+	// ToolNamingError refuses to register clashing tools
+	ToolNamingError = "error"
+)
+
+// Policies for sampling requests from MCP servers
+const (
+	SamplingAllow = "allow"
+	SamplingDeny  = "deny"
+	SamplingAsk   = "ask"
 )
 
-// MCPServerConfig holds configuration for a single MCP server
+// SamplingConfig controls how a server may use the LLM through sampling/createMessage
+type SamplingConfig struct {
+	// Policy is allow, deny or ask. Ask prompts the user in interactive mode
+	// and denies elsewhere. Defaults to ask.
+	Policy string `yaml:"policy,omitempty"`
+	// Models lists models the server may select through its model hints.
+	// Requests use the configured LLM model when no hint matches.
+	Models []string `yaml:"models,omitempty"`
+}
+
+// HealthCheckConfig controls the pings sent to check that a server still responds
+type HealthCheckConfig struct {
+	// Interval is the time between pings. Zero means the default of 30s; a
+	// negative value disables health checks.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Timeout bounds each ping. Defaults to 10s.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// FailureThreshold is how many pings in a row must fail before the
+	// server is marked unhealthy. Defaults to 3.
+	FailureThreshold int `yaml:"failure_threshold,omitempty"`
+	// Restart restarts the server once it is unhealthy
+	Restart bool `yaml:"restart,omitempty"`
+}
+
+// PingInterval returns the time between pings, or zero if health checks are disabled
+func (h HealthCheckConfig) PingInterval() time.Duration {
+	switch {
+	case h.Interval < 0:
+		return 0
+	case h.Interval == 0:
+		return DefaultPingInterval
+	default:
+		return h.Interval
+	}
+}
+
+// PingTimeout returns the timeout for each ping
+func (h HealthCheckConfig) PingTimeout() time.Duration {
+	if h.Timeout > 0 {
+		return h.Timeout
+	}
+	return DefaultPingTimeout
+}
+
+// Failures returns how many pings in a row must fail before the server is unhealthy
+func (h HealthCheckConfig) Failures() int {
+	if h.FailureThreshold > 0 {
+		return h.FailureThreshold
+	}
+	return DefaultFailureThreshold
+}
+
+// MCPServerConfig holds configuration for a single MCP server. Local servers
+// are launched from Command; remote servers are reached at URL instead.
 type MCPServerConfig struct {
 	Name      string            `yaml:"name"`
-	Command   string            `yaml:"command"`
-	Arguments []string          `yaml:"arguments"`
+	Command   string            `yaml:"command,omitempty"`
+	Arguments []string          `yaml:"arguments,omitempty"`
 	Env       map[string]string `yaml:"env,omitempty"`
+
+	// URL is the endpoint of a remote server
+	URL string `yaml:"url,omitempty"`
+	// Transport selects how to reach the server: stdio, sse or streamable-http.
+	// Defaults to stdio for a command and sse for a url.
+	Transport string `yaml:"transport,omitempty"`
+	// Headers are sent with every HTTP request to a remote server, e.g. for authentication
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Cwd is the working directory the server is started in
+	Cwd string `yaml:"cwd,omitempty"`
+	// CleanEnv starts the server with only PATH, HOME and Env instead of inheriting gomcp's environment
+	CleanEnv bool `yaml:"clean_env,omitempty"`
+
+	// Timeout bounds each request to the server, e.g. "30s"
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// ToolTimeouts overrides Timeout for individual tools, keyed by tool name
+	ToolTimeouts map[string]time.Duration `yaml:"tool_timeouts,omitempty"`
+	// ToolAliases renames the server's tools for the LLM, keyed by tool name
+	ToolAliases map[string]string `yaml:"tool_aliases,omitempty"`
+	// StartupTimeout bounds how long the server may take to launch and
+	// complete the initialize handshake. Defaults to 30s.
+	StartupTimeout time.Duration `yaml:"startup_timeout,omitempty"`
+
+	// Required makes the bridge refuse to start when the server fails to.
+	// Defaults to true; optional servers that fail are logged and skipped.
+	Required *bool `yaml:"required,omitempty"`
+	// Lazy defers starting the server until one of its tools is called,
+	// offering its tools from the cache of the last run until then
+	Lazy bool `yaml:"lazy,omitempty"`
+	// IdleTimeout stops a lazy server after it has gone this long without a tool call
+	IdleTimeout time.Duration `yaml:"idle_timeout,omitempty"`
+	// ShutdownGrace is how long the server gets to exit after its stdin is
+	// closed, and again after SIGTERM, before it is killed. Defaults to 5s.
+	ShutdownGrace time.Duration `yaml:"shutdown_grace,omitempty"`
+
+	// Sampling governs LLM completions requested by the server
+	Sampling SamplingConfig `yaml:"sampling,omitempty"`
+
+	// HealthCheck governs the pings that check the server still responds
+	HealthCheck HealthCheckConfig `yaml:"health_check,omitempty"`
+
+	// Roots are directories shared with this server only, as paths or file:// URIs
+	Roots []string `yaml:"roots,omitempty"`
+
+	// MaxRestarts caps consecutive restarts after the server crashes. Zero
+	// means the default of 5; a negative value disables restarts.
+	MaxRestarts int `yaml:"max_restarts,omitempty"`
+	// RestartBackoff is the delay before the first restart, doubling for each further attempt
+	RestartBackoff time.Duration `yaml:"restart_backoff,omitempty"`
+
+	// StderrLevel is the level the server's stderr lines are logged at: debug,
+	// info, warn, error, or off to not log them. Defaults to info.
+	StderrLevel string `yaml:"stderr_level,omitempty"`
+	// StderrLines is how many recent stderr lines are kept to explain failures
+	StderrLines int `yaml:"stderr_lines,omitempty"`
+	// LogIgnore lists regular expressions for server output not worth logging
+	LogIgnore []string `yaml:"log_ignore,omitempty"`
+	// LogLevel is the minimum level of log messages the server is asked to
+	// send, e.g. debug, info, notice, warning or error. Unset leaves the
+	// server's own default.
+	LogLevel string `yaml:"log_level,omitempty"`
+}
+
+// TransportType returns the transport used to reach the server
+func (s MCPServerConfig) TransportType() string {
+	if s.Transport != "" {
+		return s.Transport
+	}
+	if s.URL != "" {
+		return TransportSSE
+	}
+	return TransportStdio
+}
+
+// RequestTimeout returns the timeout for requests to the server
+func (s MCPServerConfig) RequestTimeout() time.Duration {
+	if s.Timeout > 0 {
+		return s.Timeout
+	}
+	return DefaultRequestTimeout
+}
+
+// StartTimeout returns how long the server may take to become ready
+func (s MCPServerConfig) StartTimeout() time.Duration {
+	if s.StartupTimeout > 0 {
+		return s.StartupTimeout
+	}
+	return DefaultStartupTimeout
+}
+
+// ShutdownGracePeriod returns how long the server gets to exit at each stage of shutdown
+func (s MCPServerConfig) ShutdownGracePeriod() time.Duration {
+	if s.ShutdownGrace > 0 {
+		return s.ShutdownGrace
+	}
+	return DefaultShutdownGrace
+}
+
+// IsRequired reports whether the bridge needs the server to start
+func (s MCPServerConfig) IsRequired() bool {
+	return s.Required == nil || *s.Required
+}
+
+// ToolTimeout returns the timeout for calls to the named tool
+func (s MCPServerConfig) ToolTimeout(tool string) time.Duration {
+	if timeout, ok := s.ToolTimeouts[tool]; ok && timeout > 0 {
+		return timeout
+	}
+	return s.RequestTimeout()
+}
+
+// RestartLimit returns how many consecutive restarts the server gets after crashing
+func (s MCPServerConfig) RestartLimit() int {
+	switch {
+	case s.MaxRestarts < 0:
+		return 0
+	case s.MaxRestarts == 0:
+		return DefaultMaxRestarts
+	default:
+		return s.MaxRestarts
+	}
+}
+
+// RestartDelay returns the delay before the first restart of the server
+func (s MCPServerConfig) RestartDelay() time.Duration {
+	if s.RestartBackoff > 0 {
+		return s.RestartBackoff
+	}
+	return DefaultRestartBackoff
+}
+
+// StderrLogLevel returns the level the server's stderr lines are logged at
+func (s MCPServerConfig) StderrLogLevel() string {
+	if s.StderrLevel != "" {
+		return strings.ToLower(s.StderrLevel)
+	}
+	return DefaultStderrLevel
+}
+
+// StderrBufferLines returns how many recent stderr lines are kept for the server
+func (s MCPServerConfig) StderrBufferLines() int {
+	if s.StderrLines > 0 {
+		return s.StderrLines
+	}
+	return DefaultStderrLines
+}
+
+// SamplingPolicy returns the policy for sampling requests from the server
+func (s MCPServerConfig) SamplingPolicy() string {
+	if s.Sampling.Policy != "" {
+		return s.Sampling.Policy
+	}
+	return SamplingAsk
 }
 
 // Config holds the complete configuration for the bridge
@@ -29,12 +295,32 @@ type Config struct {
 		Endpoint     string `yaml:"endpoint"`
 		APIKey       string `yaml:"api_key"`
 		SystemPrompt string `yaml:"system_prompt"`
+		// MaxIterations caps the rounds of tool calls made to answer one
+		// message. Defaults to 10.
+		MaxIterations int `yaml:"max_iterations,omitempty"`
+		// ParallelToolCalls caps how many tool calls from one response run
+		// at once. Defaults to 4; 1 runs them one at a time.
+		ParallelToolCalls int `yaml:"parallel_tool_calls,omitempty"`
 	} `yaml:"llm"`
 
 	MCPServers []MCPServerConfig `yaml:"mcp_servers"`
 
+	// ToolCache is the file the tools of lazy servers are cached in.
+	// Defaults to tool-cache.json next to the config file.
+	ToolCache string `yaml:"tool_cache,omitempty"`
+
+	// ToolNaming decides what happens when several servers offer tools under
+	// the same name: prefix (the default) or error
+	ToolNaming string `yaml:"tool_naming,omitempty"`
+
+	// Roots are directories every MCP server may work in, as paths or file:// URIs
+	Roots []string `yaml:"roots,omitempty"`
+
 	Database struct {
 		Path string `yaml:"path"`
+		// Sessions is the SQLite database conversations are kept in.
+		// Defaults to sessions.db next to the database at Path.
+		Sessions string `yaml:"sessions,omitempty"`
 	} `yaml:"database"`
 
 	Logging struct {
@@ -77,17 +363,17 @@ When using bybit tools:
 			Arguments: []string{"mcp-server-sqlite", "--db-path", "test.db"},
 		},
 		{
-			Name:    "bybit",
-			Command: "/bin/sh",
-			Arguments: []string{
-				"-c",
-				"pnpm run serve", // User must configure the correct path in their config file
-			},
+			Name:      "bybit",
+			Command:   "pnpm",
+			Arguments: []string{"run", "serve"},
+			Cwd:       "", // User must configure the path to their bybit-mcp checkout
+			CleanEnv:  true,
 			Env: map[string]string{
 				"BYBIT_API_KEY":       "", // Add your Bybit API **READ ONLY** key here
 				"BYBIT_API_SECRET":    "", // Add your Bybit API **READ ONLY** secret here
 				"BYBIT_USE_TESTNET":   "true",
 			},
+			LogIgnore: []string{"Running in development mode"},
 		},
 	}
 
@@ -117,6 +403,51 @@ func GetConfigPath() (string, error) {
 	return filepath.Join(configDir, defaultConfigFile), nil
 }
 
+// MaxToolIterations returns how many rounds of tool calls may be made to answer one message
+func (c *Config) MaxToolIterations() int {
+	if c.LLM.MaxIterations > 0 {
+		return c.LLM.MaxIterations
+	}
+	return DefaultMaxIterations
+}
+
+// ParallelToolLimit returns how many tool calls from one response may run at once
+func (c *Config) ParallelToolLimit() int {
+	if c.LLM.ParallelToolCalls > 0 {
+		return c.LLM.ParallelToolCalls
+	}
+	return DefaultParallelToolCalls
+}
+
+// ToolNamingStrategy returns how tools offered under the same name by several servers are named
+func (c *Config) ToolNamingStrategy() string {
+	if c.ToolNaming != "" {
+		return c.ToolNaming
+	}
+	return ToolNamingPrefix
+}
+
+// ToolCachePath returns the path of the file the tools of lazy servers are cached in
+func (c *Config) ToolCachePath() (string, error) {
+	if c.ToolCache != "" {
+		return c.ToolCache, nil
+	}
+
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), defaultToolCache), nil
+}
+
+// SessionsPath returns the path of the SQLite database conversations are kept in
+func (c *Config) SessionsPath() string {
+	if c.Database.Sessions != "" {
+		return c.Database.Sessions
+	}
+	return filepath.Join(filepath.Dir(c.Database.Path), defaultSessionsDB)
+}
+
 // LoadOrCreate loads the config file if it exists, or creates a default one if it doesn't
 func LoadOrCreate() (*Config, bool, error) {
 	configPath, err := GetConfigPath()
@@ -210,6 +541,17 @@ func (c *Config) validate() error {
 	if c.LLM.Endpoint == "" {
 		return fmt.Errorf("llm.endpoint is required")
 	}
+	if c.LLM.MaxIterations < 0 {
+		return fmt.Errorf("llm.max_iterations must not be negative")
+	}
+	if c.LLM.ParallelToolCalls < 0 {
+		return fmt.Errorf("llm.parallel_tool_calls must not be negative")
+	}
+	switch c.ToolNamingStrategy() {
+	case ToolNamingPrefix, ToolNamingError:
+	default:
+		return fmt.Errorf("tool_naming must be %s or %s", ToolNamingPrefix, ToolNamingError)
+	}
 
 	// Required MCP fields
 	if len(c.MCPServers) == 0 {
@@ -219,8 +561,90 @@ func (c *Config) validate() error {
 		if server.Name == "" {
 			return fmt.Errorf("mcp_servers[%d].name is required", i)
 		}
-		if server.Command == "" {
-			return fmt.Errorf("mcp_servers[%d].command is required", i)
+		if server.Command == "" && server.URL == "" {
+			return fmt.Errorf("mcp_servers[%d] requires either command or url", i)
+		}
+		if server.Command != "" && server.URL != "" {
+			return fmt.Errorf("mcp_servers[%d] must set only one of command and url", i)
+		}
+		switch server.TransportType() {
+		case TransportStdio:
+			if server.Command == "" {
+				return fmt.Errorf("mcp_servers[%d].command is required for the stdio transport", i)
+			}
+		case TransportSSE, TransportStreamableHTTP:
+			if server.URL == "" {
+				return fmt.Errorf("mcp_servers[%d].url is required for the %s transport", i, server.TransportType())
+			}
+		default:
+			return fmt.Errorf("mcp_servers[%d].transport must be one of %s, %s or %s", i,
+				TransportStdio, TransportSSE, TransportStreamableHTTP)
+		}
+		if server.Timeout < 0 {
+			return fmt.Errorf("mcp_servers[%d].timeout must not be negative", i)
+		}
+		if server.StartupTimeout < 0 {
+			return fmt.Errorf("mcp_servers[%d].startup_timeout must not be negative", i)
+		}
+		if server.IdleTimeout < 0 {
+			return fmt.Errorf("mcp_servers[%d].idle_timeout must not be negative", i)
+		}
+		if server.IdleTimeout > 0 && !server.Lazy {
+			return fmt.Errorf("mcp_servers[%d].idle_timeout requires lazy: true", i)
+		}
+		if server.ShutdownGrace < 0 {
+			return fmt.Errorf("mcp_servers[%d].shutdown_grace must not be negative", i)
+		}
+		if server.HealthCheck.Timeout < 0 {
+			return fmt.Errorf("mcp_servers[%d].health_check.timeout must not be negative", i)
+		}
+		if server.HealthCheck.FailureThreshold < 0 {
+			return fmt.Errorf("mcp_servers[%d].health_check.failure_threshold must not be negative", i)
+		}
+		if server.RestartBackoff < 0 {
+			return fmt.Errorf("mcp_servers[%d].restart_backoff must not be negative", i)
+		}
+		switch server.StderrLogLevel() {
+		case "debug", "info", "warn", "error", "off":
+		default:
+			return fmt.Errorf("mcp_servers[%d].stderr_level must be one of debug, info, warn, error or off", i)
+		}
+		if server.StderrLines < 0 {
+			return fmt.Errorf("mcp_servers[%d].stderr_lines must not be negative", i)
+		}
+		switch server.LogLevel {
+		case "", "debug", "info", "notice", "warning", "error", "critical", "alert", "emergency":
+		default:
+			return fmt.Errorf("mcp_servers[%d].log_level must be one of debug, info, notice, warning, error, critical, alert or emergency", i)
+		}
+		for j, pattern := range server.LogIgnore {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("mcp_servers[%d].log_ignore[%d]: %w", i, j, err)
+			}
+		}
+		for tool, alias := range server.ToolAliases {
+			if alias == "" {
+				return fmt.Errorf("mcp_servers[%d].tool_aliases.%s must not be empty", i, tool)
+			}
+		}
+		switch server.SamplingPolicy() {
+		case SamplingAllow, SamplingDeny, SamplingAsk:
+		default:
+			return fmt.Errorf("mcp_servers[%d].sampling.policy must be one of %s, %s or %s", i,
+				SamplingAllow, SamplingDeny, SamplingAsk)
+		}
+	}
+
+	for i, root := range c.Roots {
+		if err := validateRoot(root); err != nil {
+			return fmt.Errorf("roots[%d]: %w", i, err)
+		}
+	}
+	for i, server := range c.MCPServers {
+		for j, root := range server.Roots {
+			if err := validateRoot(root); err != nil {
+				return fmt.Errorf("mcp_servers[%d].roots[%d]: %w", i, j, err)
+			}
 		}
 	}
 
@@ -231,3 +655,14 @@ func (c *Config) validate() error {
 
 	return nil
 }
+
+// validateRoot checks that a root is a path or a file:// URI
+func validateRoot(root string) error {
+	if root == "" {
+		return fmt.Errorf("root must not be empty")
+	}
+	if strings.Contains(root, "://") && !strings.HasPrefix(root, "file://") {
+		return fmt.Errorf("root %q must be a path or a file:// URI", root)
+	}
+	return nil
+}
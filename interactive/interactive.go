@@ -3,10 +3,14 @@ package interactive
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
 
 	"github.com/sammcj/gomcp/bridge"
 	"github.com/sammcj/gomcp/config"
@@ -18,15 +22,30 @@ type Interactive struct {
     cfg     *config.Config
     bridge  *bridge.Bridge
     debug   bool
+
+    attached []string // URIs of resources included with every message
+    session  string   // ID of the conversation messages are sent in
+
+    // Lines typed by the user go to approval while a sampling request waits
+    // for an answer, or else are queued in typed for the chat loop. Queueing
+    // them means lines typed ahead during a turn never hold up an approval.
+    inputMu    sync.Mutex
+    inputReady *sync.Cond // signalled when typed grows or stdin closes
+    typed      []string
+    inputDone  bool
+    approval   chan string
+    approvalMu sync.Mutex // serialises sampling approvals
 }
 
 func New(cfg *config.Config) *Interactive {
-    return &Interactive{
+    i := &Interactive{
         scanner: bufio.NewReader(os.Stdin),
         logger:  log.Default(),
         cfg:     cfg,
         debug:   strings.ToLower(cfg.Logging.Level) == "debug",
     }
+    i.inputReady = sync.NewCond(&i.inputMu)
+    return i
 }
 
 func (i *Interactive) Start() error {
@@ -43,21 +62,39 @@ func (i *Interactive) Start() error {
         return fmt.Errorf("failed to initialize bridge: %w", err)
     }
 
+    // Start a new conversation; /resume switches back to an earlier one
+    i.session, err = i.bridge.NewSession()
+    if err != nil {
+        return fmt.Errorf("failed to start session: %w", err)
+    }
+
     fmt.Println("\n=== Ollama Chat Interface Ready ===")
-    fmt.Println("Type 'quit' or press Ctrl+C to exit")
+    fmt.Println("Type 'quit' or press Ctrl+C to exit; Ctrl+C while waiting for an answer cancels it")
     fmt.Println("Connected to model:", i.cfg.LLM.Model)
     fmt.Printf("Using endpoint: %s\n", i.cfg.LLM.Endpoint)
     fmt.Println("Database:", i.cfg.Database.Path)
+    fmt.Printf("Session: %s (continue it later with /resume %s)\n", i.session, i.session)
+    fmt.Println("Commands: /resources, /attach <uri>, /detach <uri>, /prompts, /<server>:<prompt> [arg=value ...], /usage, /roots [add|remove <path>], /servers, /session, /new, /resume <id>")
     fmt.Println("================================")
 
+    i.bridge.SetResourceUpdateHandler(func(server, uri string) {
+        fmt.Printf("\n[resource %s on %s was updated]\n", uri, server)
+    })
+    i.bridge.SetSamplingApprover(i.approveSampling)
+    i.bridge.SetServerStateHandler(func(status bridge.ServerStatus) {
+        if (status.State != bridge.StateReady && status.State != bridge.StateIdle) || status.Restarts > 0 {
+            fmt.Printf("\n[MCP server %s is %s]\n", status.Name, status.State)
+        }
+    })
+
+    go i.readInput()
+
     for {
         fmt.Print("\nEnter your message: ")
-        input, err := i.scanner.ReadString('\n')
-        if err != nil {
-            if i.debug {
-                i.logger.Printf("Error reading input: %v", err)
-            }
-            continue
+        input, ok := i.nextLine()
+        if !ok {
+            fmt.Println("Goodbye!")
+            return nil
         }
 
         input = strings.TrimSpace(input)
@@ -70,11 +107,31 @@ func (i *Interactive) Start() error {
             return nil
         }
 
+        if strings.HasPrefix(input, "/") {
+            i.handleCommand(input)
+            continue
+        }
+
+        message, err := i.withAttachments(input)
+        if err != nil {
+            fmt.Printf("\nError: %v\n", err)
+            continue
+        }
+
         // Process message through bridge
         if i.debug {
             i.logger.Printf("Sending message to bridge: %s", input)
         }
-        response, err := i.bridge.ProcessMessage(input)
+        ctx, cancel := i.turnContext()
+        answer, err := i.bridge.Chat(i.withProgress(ctx), i.session, message)
+        cancel()
+        if answer != nil {
+            printSteps(answer.Steps)
+        }
+        if errors.Is(err, context.Canceled) {
+            fmt.Println("\nCancelled.")
+            continue
+        }
         if err != nil {
             if i.debug {
                 i.logger.Printf("Error from bridge: %v", err)
@@ -83,6 +140,7 @@ func (i *Interactive) Start() error {
             continue
         }
 
+        response := answer.Content
         if response == "" {
             if i.debug {
                 i.logger.Printf("Warning: Empty response received from bridge")
@@ -96,6 +154,336 @@ func (i *Interactive) Start() error {
     }
 }
 
+// readInput reads lines from stdin until it closes, handing each to a pending
+// sampling approval or else queueing it for the chat loop
+func (i *Interactive) readInput() {
+    for {
+        input, err := i.scanner.ReadString('\n')
+        if err != nil {
+            if i.debug {
+                i.logger.Printf("Error reading input: %v", err)
+            }
+            i.inputMu.Lock()
+            i.inputDone = true
+            i.inputReady.Broadcast()
+            i.inputMu.Unlock()
+            return
+        }
+
+        i.inputMu.Lock()
+        if i.approval != nil {
+            // The answer channel is buffered, so this never blocks
+            i.approval <- input
+            i.approval = nil
+        } else {
+            i.typed = append(i.typed, input)
+            i.inputReady.Signal()
+        }
+        i.inputMu.Unlock()
+    }
+}
+
+// nextLine waits for the next line typed for the chat loop, returning false
+// once stdin has closed and every line has been taken
+func (i *Interactive) nextLine() (string, bool) {
+    i.inputMu.Lock()
+    defer i.inputMu.Unlock()
+
+    for len(i.typed) == 0 && !i.inputDone {
+        i.inputReady.Wait()
+    }
+    if len(i.typed) == 0 {
+        return "", false
+    }
+    input := i.typed[0]
+    i.typed = i.typed[1:]
+    return input, true
+}
+
+// approveSampling asks the user whether a server may run a completion
+func (i *Interactive) approveSampling(ctx context.Context, req bridge.SamplingRequest) bool {
+    i.approvalMu.Lock()
+    defer i.approvalMu.Unlock()
+
+    answer := make(chan string, 1)
+    i.inputMu.Lock()
+    i.approval = answer
+    i.inputMu.Unlock()
+    defer func() {
+        i.inputMu.Lock()
+        if i.approval == answer {
+            i.approval = nil
+        }
+        i.inputMu.Unlock()
+    }()
+
+    fmt.Printf("\n=== Server %s requests an LLM completion ===\n", req.Server)
+    if req.SystemPrompt != "" {
+        fmt.Printf("System prompt: %s\n", req.SystemPrompt)
+    }
+    for _, msg := range req.Messages {
+        if len(msg.Images) > 0 {
+            fmt.Printf("[%s] <%d image(s)>\n", msg.Role, len(msg.Images))
+            continue
+        }
+        fmt.Printf("[%s] %s\n", msg.Role, msg.Content)
+    }
+    if req.MaxTokens > 0 {
+        fmt.Printf("Max tokens: %d\n", req.MaxTokens)
+    }
+    if req.Model != "" {
+        fmt.Printf("Model: %s\n", req.Model)
+    }
+    fmt.Print("Allow? [y/N]: ")
+
+    select {
+    case input := <-answer:
+        input = strings.ToLower(strings.TrimSpace(input))
+        return input == "y" || input == "yes"
+    case <-ctx.Done():
+        fmt.Println("\nSampling request withdrawn by the server")
+        return false
+    }
+}
+
+// handleCommand runs a slash command entered at the prompt
+func (i *Interactive) handleCommand(input string) {
+    command, arg, _ := strings.Cut(input, " ")
+    arg = strings.TrimSpace(arg)
+
+    // Server prompts are run as /server:prompt arg=value
+    if server, name, ok := strings.Cut(strings.TrimPrefix(command, "/"), ":"); ok {
+        i.runPrompt(server, name, arg)
+        return
+    }
+
+    switch command {
+    case "/roots":
+        action, path, _ := strings.Cut(arg, " ")
+        path = strings.TrimSpace(path)
+        switch {
+        case action == "":
+            fmt.Printf("\n%s\n", bridge.FormatRoots(i.bridge.Roots("")))
+        case action == "add" && path != "":
+            if err := i.bridge.AddRoot(context.Background(), path); err != nil {
+                fmt.Printf("\nError: %v\n", err)
+                return
+            }
+            fmt.Printf("\nShared %s with MCP servers\n", path)
+        case action == "remove" && path != "":
+            if err := i.bridge.RemoveRoot(context.Background(), path); err != nil {
+                fmt.Printf("\nError: %v\n", err)
+                return
+            }
+            fmt.Printf("\nStopped sharing %s\n", path)
+        default:
+            fmt.Println("\nUsage: /roots [add|remove <path>]")
+        }
+
+    case "/session":
+        fmt.Printf("\nSession: %s\n", i.session)
+
+    case "/new":
+        session, err := i.bridge.NewSession()
+        if err != nil {
+            fmt.Printf("\nError: %v\n", err)
+            return
+        }
+        i.session = session
+        fmt.Printf("\nStarted session %s\n", session)
+
+    case "/resume":
+        if arg == "" {
+            fmt.Println("\nUsage: /resume <id>")
+            return
+        }
+        history, err := i.bridge.SessionMessages(arg)
+        if err != nil {
+            fmt.Printf("\nError: %v\n", err)
+            return
+        }
+        i.session = arg
+        fmt.Printf("\nResumed session %s with %d earlier messages\n", arg, len(history))
+
+    case "/servers":
+        fmt.Printf("\n%s\n", bridge.FormatServerStatuses(i.bridge.ServerStatuses()))
+
+    case "/usage":
+        fmt.Printf("\n%s\n", bridge.FormatSamplingUsage(i.bridge.SamplingUsage()))
+
+    case "/prompts":
+        fmt.Printf("\n%s\n", bridge.FormatPromptList(i.bridge.ListPrompts()))
+
+    case "/resources":
+        fmt.Printf("\n%s\n", bridge.FormatResourceList(i.bridge.ListResources()))
+
+    case "/attach":
+        if arg == "" {
+            fmt.Println("\nUsage: /attach <uri>")
+            return
+        }
+        if _, err := i.bridge.ReadResource(context.Background(), "", arg); err != nil {
+            fmt.Printf("\nError: %v\n", err)
+            return
+        }
+        for _, uri := range i.attached {
+            if uri == arg {
+                fmt.Printf("\n%s is already attached\n", arg)
+                return
+            }
+        }
+        i.attached = append(i.attached, arg)
+
+        // Subscriptions are optional, so attaching still works without them
+        if err := i.bridge.SubscribeResource(context.Background(), "", arg); err != nil && i.debug {
+            i.logger.Printf("Not subscribed to %s: %v", arg, err)
+        }
+        fmt.Printf("\nAttached %s\n", arg)
+
+    case "/detach":
+        for n, uri := range i.attached {
+            if uri == arg {
+                i.attached = append(i.attached[:n], i.attached[n+1:]...)
+                if err := i.bridge.UnsubscribeResource(context.Background(), "", arg); err != nil && i.debug {
+                    i.logger.Printf("Failed to unsubscribe from %s: %v", arg, err)
+                }
+                fmt.Printf("\nDetached %s\n", arg)
+                return
+            }
+        }
+        fmt.Printf("\n%s is not attached\n", arg)
+
+    default:
+        fmt.Printf("\nUnknown command: %s\n", command)
+    }
+}
+
+// printSteps lists the tool calls made while answering a message
+func printSteps(steps []bridge.Step) {
+    for _, step := range steps {
+        if step.IsError {
+            fmt.Printf("\n[%s failed]", step.Tool)
+            continue
+        }
+        fmt.Printf("\n[used %s]", step.Tool)
+    }
+    if len(steps) > 0 {
+        fmt.Println()
+    }
+}
+
+// turnContext returns a context for one turn of the conversation that Ctrl+C
+// cancels, so a slow model or tool can be cut short without quitting.
+// Calling the returned function ends the turn and restores Ctrl+C.
+func (i *Interactive) turnContext() (context.Context, context.CancelFunc) {
+    ctx, cancel := context.WithCancel(context.Background())
+    interrupts := make(chan os.Signal, 1)
+    signal.Notify(interrupts, os.Interrupt)
+
+    go func() {
+        select {
+        case <-interrupts:
+            cancel()
+        case <-ctx.Done():
+        }
+    }()
+
+    return ctx, func() {
+        signal.Stop(interrupts)
+        cancel()
+    }
+}
+
+// withProgress shows the progress of tool calls made under ctx as it happens
+func (i *Interactive) withProgress(ctx context.Context) context.Context {
+    return bridge.WithProgress(ctx, func(p bridge.Progress) {
+        fmt.Printf("\n[%s]", bridge.FormatProgress(p))
+    })
+}
+
+// runPrompt expands a server prompt and sends its messages to the model
+func (i *Interactive) runPrompt(server, name, argString string) {
+    args, err := parsePromptArgs(argString)
+    if err != nil {
+        fmt.Printf("\nError: %v\n", err)
+        return
+    }
+
+    ctx, cancel := i.turnContext()
+    answer, err := i.bridge.RunPromptInSession(i.withProgress(ctx), i.session, server, name, args)
+    cancel()
+    if errors.Is(err, context.Canceled) {
+        fmt.Println("\nCancelled.")
+        return
+    }
+    if err != nil {
+        if i.debug {
+            i.logger.Printf("Error running prompt %s:%s: %v", server, name, err)
+        }
+        fmt.Printf("\nError: %v\n", err)
+        return
+    }
+    fmt.Printf("\n%s\n", answer.Content)
+}
+
+// parsePromptArgs parses space-separated key=value pairs. Values containing
+// spaces can be wrapped in double quotes.
+func parsePromptArgs(input string) (map[string]string, error) {
+    args := make(map[string]string)
+
+    var fields []string
+    var current strings.Builder
+    inQuotes := false
+    for _, r := range input {
+        switch {
+        case r == '"':
+            inQuotes = !inQuotes
+        case r == ' ' && !inQuotes:
+            if current.Len() > 0 {
+                fields = append(fields, current.String())
+                current.Reset()
+            }
+        default:
+            current.WriteRune(r)
+        }
+    }
+    if inQuotes {
+        return nil, fmt.Errorf("unterminated quote in arguments")
+    }
+    if current.Len() > 0 {
+        fields = append(fields, current.String())
+    }
+
+    for _, field := range fields {
+        key, value, ok := strings.Cut(field, "=")
+        if !ok || key == "" {
+            return nil, fmt.Errorf("invalid argument %q, expected key=value", field)
+        }
+        args[key] = value
+    }
+    return args, nil
+}
+
+// withAttachments prepends the current contents of attached resources to a message
+func (i *Interactive) withAttachments(input string) (string, error) {
+    if len(i.attached) == 0 {
+        return input, nil
+    }
+
+    var sb strings.Builder
+    sb.WriteString("Attached resources:\n")
+    for _, uri := range i.attached {
+        contents, err := i.bridge.ReadResource(context.Background(), "", uri)
+        if err != nil {
+            return "", fmt.Errorf("failed to read attached resource %s: %w", uri, err)
+        }
+        sb.WriteString(bridge.FormatResourceContents(contents))
+        sb.WriteString("\n\n")
+    }
+    sb.WriteString(input)
+    return sb.String(), nil
+}
+
 func (i *Interactive) Shutdown() error {
     if i.bridge != nil {
         return i.bridge.Close()
@@ -2,11 +2,13 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"sync"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/sammcj/gomcp/types"
@@ -18,16 +20,19 @@ type Client struct {
 	model        string
 	systemPrompt string
 	httpClient   *http.Client
-	tools        []mcp.Tool
 	logger       *log.Logger
+
+	toolsMu sync.RWMutex
+	tools   []mcp.Tool
 }
 
 // Request represents a request to the Ollama API
 type Request struct {
-	Model    string          `json:"model"`
-	Messages []types.Message `json:"messages"`
-	Stream   bool            `json:"stream"`
-	Tools    []interface{}   `json:"tools,omitempty"`
+	Model    string                 `json:"model"`
+	Messages []types.Message        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Tools    []interface{}          `json:"tools,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
 }
 
 // Response represents a response from the Ollama API
@@ -38,6 +43,32 @@ type Response struct {
 		Content   string           `json:"content"`
 		ToolCalls []types.ToolCall `json:"tool_calls,omitempty"`
 	} `json:"message"`
+	DoneReason      string `json:"done_reason,omitempty"`
+	PromptEvalCount int    `json:"prompt_eval_count,omitempty"`
+	EvalCount       int    `json:"eval_count,omitempty"`
+}
+
+// CompletionRequest asks for a single completion outside the chat, as MCP
+// servers do through sampling
+type CompletionRequest struct {
+	// Model overrides the configured model when set
+	Model string
+	// SystemPrompt replaces the configured system prompt
+	SystemPrompt  string
+	Messages      []types.Message
+	MaxTokens     int
+	Temperature   *float64
+	StopSequences []string
+}
+
+// Completion is the model's answer to a CompletionRequest
+type Completion struct {
+	Content string
+	Model   string
+	// StopReason is why generation ended, e.g. "stop" or "length"
+	StopReason       string
+	PromptTokens     int
+	CompletionTokens int
 }
 
 // New creates a new Ollama client
@@ -51,22 +82,39 @@ func New(endpoint, model, systemPrompt string) *Client {
 	}
 }
 
-// SetTools configures the available tools for the model
+// SetTools configures the available tools for the model. It may be called
+// again at any time to replace them.
 func (c *Client) SetTools(tools []mcp.Tool) error {
+	c.toolsMu.Lock()
+	defer c.toolsMu.Unlock()
 	c.tools = tools
 	return nil
 }
 
 // GenerateResponse sends a message to the model and gets its response
-func (c *Client) GenerateResponse(msg string) (*types.LLMResponse, error) {
+func (c *Client) GenerateResponse(ctx context.Context, msg string) (*types.LLMResponse, error) {
+	return c.GenerateChat(ctx, []types.Message{{Role: "user", Content: msg}})
+}
+
+// GenerateChat sends a conversation to the model, after the system prompt,
+// and gets its response. Cancelling ctx abandons the generation.
+func (c *Client) GenerateChat(ctx context.Context, conversation []types.Message) (*types.LLMResponse, error) {
 	// Convert MCP tools to Ollama format
-	ollamaTools := c.convertTools()
+	return c.generateChat(ctx, conversation, c.convertTools())
+}
+
+// GenerateChatWithoutTools is GenerateChat with no tools offered, so the
+// model has to answer in text
+func (c *Client) GenerateChatWithoutTools(ctx context.Context, conversation []types.Message) (*types.LLMResponse, error) {
+	return c.generateChat(ctx, conversation, nil)
+}
 
+// generateChat sends a conversation to the model offering the given tools
+func (c *Client) generateChat(ctx context.Context, conversation []types.Message, ollamaTools []interface{}) (*types.LLMResponse, error) {
 	// Build messages array with system prompt
-	messages := []types.Message{
+	messages := append([]types.Message{
 		{Role: "system", Content: c.systemPrompt},
-		{Role: "user", Content: msg},
-	}
+	}, conversation...)
 
 	// Create request
 	req := Request{
@@ -77,7 +125,7 @@ func (c *Client) GenerateResponse(msg string) (*types.LLMResponse, error) {
 	}
 
 	// Send request
-	resp, err := c.sendRequest(req)
+	resp, err := c.sendRequest(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -85,40 +133,78 @@ func (c *Client) GenerateResponse(msg string) (*types.LLMResponse, error) {
 	return resp, nil
 }
 
-// ContinueWithToolResults continues the conversation after tool execution
-func (c *Client) ContinueWithToolResults(toolResults []map[string]interface{}) (*types.LLMResponse, error) {
+// Complete runs a completion without tools, using the request's own system
+// prompt and limits
+func (c *Client) Complete(ctx context.Context, completion CompletionRequest) (*Completion, error) {
+	model := completion.Model
+	if model == "" {
+		model = c.model
+	}
+
 	var messages []types.Message
-	if c.systemPrompt != "" {
-		messages = append(messages, types.Message{
-			Role:    "system",
-			Content: c.systemPrompt,
-		})
+	if completion.SystemPrompt != "" {
+		messages = append(messages, types.Message{Role: "system", Content: completion.SystemPrompt})
 	}
+	messages = append(messages, completion.Messages...)
 
-	// Add each tool result as a separate message
-	for _, result := range toolResults {
-		toolCallID := result["tool_call_id"].(string)
-		output := result["output"].(string)
-		messages = append(messages, types.Message{
-			Role:    "assistant",
-			Content: fmt.Sprintf("Tool result for call %s: %s", toolCallID, output),
-		})
+	options := make(map[string]interface{})
+	if completion.MaxTokens > 0 {
+		options["num_predict"] = completion.MaxTokens
+	}
+	if completion.Temperature != nil {
+		options["temperature"] = *completion.Temperature
+	}
+	if len(completion.StopSequences) > 0 {
+		options["stop"] = completion.StopSequences
 	}
 
-	// Create request
-	req := Request{
-		Model:    c.model,
+	resp, err := c.chat(ctx, Request{
+		Model:    model,
 		Messages: messages,
 		Stream:   false,
-		Tools:    c.convertTools(),
+		Options:  options,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
-	// Send request
-	return c.sendRequest(req)
+	return &Completion{
+		Content:          resp.Message.Content,
+		Model:            resp.Model,
+		StopReason:       resp.DoneReason,
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+	}, nil
+}
+
+// ContinueWithToolResults continues a conversation that ends with the
+// model's tool calls, passing it their results
+func (c *Client) ContinueWithToolResults(ctx context.Context, conversation []types.Message, toolResults []map[string]interface{}) (*types.LLMResponse, error) {
+	messages := append(append([]types.Message(nil), conversation...), ToolResultMessages(toolResults)...)
+	return c.GenerateChat(ctx, messages)
+}
+
+// ToolResultMessages converts tool results to tool role messages tied to the
+// calls they answer. Each result has a tool_call_id and output, and may have
+// the tool's name and images.
+func ToolResultMessages(toolResults []map[string]interface{}) []types.Message {
+	var messages []types.Message
+	for _, result := range toolResults {
+		msg := types.Message{Role: "tool"}
+		msg.ToolCallID, _ = result["tool_call_id"].(string)
+		msg.ToolName, _ = result["name"].(string)
+		msg.Content, _ = result["output"].(string)
+		msg.Images, _ = result["images"].([]string)
+		messages = append(messages, msg)
+	}
+	return messages
 }
 
 // convertTools converts MCP tools to Ollama format
 func (c *Client) convertTools() []interface{} {
+	c.toolsMu.RLock()
+	defer c.toolsMu.RUnlock()
+
 	var ollamaTools []interface{}
 
 	for _, tool := range c.tools {
@@ -141,7 +227,24 @@ func (c *Client) convertTools() []interface{} {
 }
 
 // sendRequest sends a request to the Ollama API
-func (c *Client) sendRequest(req Request) (*types.LLMResponse, error) {
+func (c *Client) sendRequest(ctx context.Context, req Request) (*types.LLMResponse, error) {
+	ollamaResp, err := c.chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to types.LLMResponse
+	result := &types.LLMResponse{
+		Content:   ollamaResp.Message.Content,
+		ToolCalls: ollamaResp.Message.ToolCalls,
+	}
+
+	c.logger.Printf("Converted response: %+v", result)
+	return result, nil
+}
+
+// chat posts a request to the Ollama chat endpoint and decodes the reply
+func (c *Client) chat(ctx context.Context, req Request) (*Response, error) {
 
 	data, err := json.Marshal(req)
 	if err != nil {
@@ -154,7 +257,7 @@ func (c *Client) sendRequest(req Request) (*types.LLMResponse, error) {
 	// c.logger.Printf("Sending request to Ollama endpoint: %s", endpoint)
 	// c.logger.Printf("Request data: %s", string(data))
 
-	httpReq, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(data))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -182,15 +285,7 @@ func (c *Client) sendRequest(req Request) (*types.LLMResponse, error) {
 	if err := json.Unmarshal(body, &ollamaResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
-
-	// Convert to types.LLMResponse
-	result := &types.LLMResponse{
-		Content:   ollamaResp.Message.Content,
-		ToolCalls: ollamaResp.Message.ToolCalls,
-	}
-
-	c.logger.Printf("Converted response: %+v", result)
-	return result, nil
+	return &ollamaResp, nil
 }
 
 // sanitizeToolName converts a tool name to a format compatible with Ollama
@@ -13,6 +13,10 @@ import (
 	"github.com/sammcj/gomcp/config"
 )
 
+// progressBuffer is how many progress updates a streamed chat holds for a
+// client that is slow to read them
+const progressBuffer = 64
+
 // Server represents the HTTP server for the bridge
 type Server struct {
 	cfg    *config.Config
@@ -22,13 +26,24 @@ type Server struct {
 
 // MessageRequest represents an incoming message request
 type MessageRequest struct {
-	Message string `json:"message"`
+	Message   string `json:"message"`
+	SessionID string `json:"session_id,omitempty"` // continues an earlier conversation; a new one is started if empty
 }
 
 // MessageResponse represents the response to a message
 type MessageResponse struct {
-	Response string `json:"response"`
-	Error    string `json:"error,omitempty"`
+	Response  string        `json:"response"`
+	SessionID string        `json:"session_id,omitempty"`
+	Steps     []bridge.Step `json:"steps,omitempty"` // tool calls made to reach the response
+	Error     string        `json:"error,omitempty"`
+}
+
+// PromptRequest represents a request to run an MCP prompt
+type PromptRequest struct {
+	Server    string            `json:"server,omitempty"`
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+	SessionID string            `json:"session_id,omitempty"` // runs the prompt in an earlier conversation; it is run on its own if empty
 }
 
 // New creates a new server instance
@@ -54,6 +69,9 @@ func (s *Server) Start() error {
 	// Set up HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/chat", s.handleChat)
+	mux.HandleFunc("/api/chat/stream", s.handleChatStream)
+	mux.HandleFunc("/api/prompts", s.handlePrompts)
+	mux.HandleFunc("/api/prompts/run", s.handleRunPrompt)
 	mux.HandleFunc("/health", s.handleHealth)
 
 	s.srv = &http.Server{
@@ -102,13 +120,150 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := s.bridge.ProcessMessage(req.Message)
-	resp := MessageResponse{
-		Response: response,
+	resp := s.processMessage(r.Context(), req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleChatStream processes a chat message like handleChat, streaming the
+// progress of tool calls as server-sent events before the response
+func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var req MessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event string, data interface{}) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		flusher.Flush()
+	}
+
+	// Progress arrives on the goroutines reading from MCP servers, which
+	// mustn't wait on a slow client, so it is queued for this goroutine to
+	// write and dropped when the queue is full
+	progress := make(chan bridge.Progress, progressBuffer)
+	ctx := bridge.WithProgress(r.Context(), func(p bridge.Progress) {
+		select {
+		case progress <- p:
+		default:
+		}
+	})
+
+	done := make(chan MessageResponse, 1)
+	go func() {
+		done <- s.processMessage(ctx, req)
+	}()
+
+	for {
+		select {
+		case p := <-progress:
+			send("progress", p)
+		case resp := <-done:
+			// Write what was queued before the response
+			for len(progress) > 0 {
+				send("progress", <-progress)
+			}
+			send("response", resp)
+			return
+		}
+	}
+}
+
+// processMessage runs a user message through the bridge in its session,
+// reporting the tool calls made along the way
+func (s *Server) processMessage(ctx context.Context, req MessageRequest) MessageResponse {
+	resp := MessageResponse{SessionID: req.SessionID}
+	if resp.SessionID == "" {
+		id, err := s.bridge.NewSession()
+		if err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		resp.SessionID = id
+	}
+
+	answer, err := s.bridge.Chat(ctx, resp.SessionID, req.Message)
+	if answer != nil {
+		resp.Response = answer.Content
+		resp.Steps = answer.Steps
 	}
 	if err != nil {
 		resp.Error = err.Error()
 	}
+	return resp
+}
+
+// handlePrompts lists the prompts offered by the MCP servers
+func (s *Server) handlePrompts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prompts := s.bridge.ListPrompts()
+	if prompts == nil {
+		prompts = []bridge.ServerPrompt{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"prompts": prompts,
+	})
+}
+
+// handleRunPrompt expands an MCP prompt and processes it through the LLM
+func (s *Server) handleRunPrompt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var resp MessageResponse
+	if req.SessionID != "" {
+		resp.SessionID = req.SessionID
+		answer, err := s.bridge.RunPromptInSession(r.Context(), req.SessionID, req.Server, req.Name, req.Arguments)
+		if answer != nil {
+			resp.Response = answer.Content
+			resp.Steps = answer.Steps
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+	} else {
+		response, err := s.bridge.RunPrompt(r.Context(), req.Server, req.Name, req.Arguments)
+		resp.Response = response
+		if err != nil {
+			resp.Error = err.Error()
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -121,9 +276,29 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A required server that has failed degrades the bridge as a whole. A
+	// failed optional server is listed on its own, and a server still
+	// starting is not counted as a failure.
+	status := "ok"
+	optionalFailures := []string{}
+	servers := s.bridge.ServerStatuses()
+	for _, server := range servers {
+		switch server.State {
+		case bridge.StateReady, bridge.StateIdle, bridge.StateStarting:
+			continue
+		}
+		if server.Required {
+			status = "degraded"
+		} else {
+			optionalFailures = append(optionalFailures, server.Name)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "ok",
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":            status,
+		"optional_failures": optionalFailures,
+		"servers":           servers,
 	})
 }
 
@@ -21,6 +21,9 @@ var (
 
 	// ErrDatabaseQuery indicates a database query error
 	ErrDatabaseQuery = errors.New("database query failed")
+
+	// ErrServerRejected indicates an MCP server answered a request with a JSON-RPC error
+	ErrServerRejected = errors.New("server rejected request")
 )
 
 // ConfigError wraps configuration-related errors
@@ -92,8 +95,29 @@ func (e *ToolError) Error() string {
 	return fmt.Sprintf("tool error in %s: %s", e.Tool, e.Message)
 }
 
-func (e *ToolError) Unwrap() error {
-	return ErrToolExecution
+func (e *ToolError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{ErrToolExecution, e.Err}
+	}
+	return []error{ErrToolExecution}
+}
+
+// RPCError represents a JSON-RPC error object returned by an MCP server
+type RPCError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	if e.Data != nil {
+		return fmt.Sprintf("JSON-RPC error %d: %s (data: %v)", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+func (e *RPCError) Unwrap() error {
+	return ErrServerRejected
 }
 
 // DatabaseError wraps database-related errors
@@ -21,5 +21,10 @@ type LLMResponse struct {
 type Message struct {
 	Role      string     `json:"role"`
 	Content   string     `json:"content"`
+	Images    []string   `json:"images,omitempty"` // base64-encoded images
 	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+
+	// ToolCallID and ToolName tie a tool role message to the call it answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	ToolName   string `json:"tool_name,omitempty"`
 }